@@ -0,0 +1,163 @@
+// handlers/barcodes.go
+package handlers
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Printed sizes for overlay codes, in mm. 0.25 mm bars and 0.5 mm QR modules
+// read reliably with handheld pack-station scanners.
+const (
+	barcodeModuleMM = 0.25
+	barcodeHeightMM = 9.0
+	qrModuleMM      = 0.5
+)
+
+// overlayQRPayload is the text encoded in an overlay line's QR code
+func overlayQRPayload(order PDFOrderData) string {
+	sku := order.CatalogSKU
+	if sku == "" {
+		sku = order.SKUID
+	}
+	return fmt.Sprintf("ORD:%s;SKU:%s;DIM:%s", order.OrderNumber, sku, order.Dimension)
+}
+
+// drawOverlayCodes draws the scannable codes for orders[i] right to left
+// from right: its QR code, then the order barcode on the order's first line.
+// The barcode stays right of minLeft. It returns the left edge of the codes,
+// which is right when none are drawn.
+func drawOverlayCodes(pdf *gofpdf.Fpdf, orders []PDFOrderData, i int, opts overlayOptions, y, right, minLeft float64) (float64, error) {
+	order := orders[i]
+	left := right
+
+	if opts.QRCode {
+		payload := overlayQRPayload(order)
+		side, err := qrCodeSize(payload)
+		if err != nil {
+			return 0, err
+		}
+		left -= side
+		if _, err := drawQRCode(pdf, payload, left, y-3); err != nil {
+			return 0, err
+		}
+	}
+
+	if opts.Barcode && (i == 0 || orders[i-1].OrderNumber != order.OrderNumber) {
+		width, err := code128Width(order.OrderNumber)
+		if err != nil {
+			return 0, err
+		}
+		if left < right {
+			left -= 2
+		}
+		left = math.Max(left-width, minLeft)
+		if _, err := drawCode128(pdf, order.OrderNumber, left, y-1); err != nil {
+			return 0, err
+		}
+	}
+
+	return left, nil
+}
+
+// code128Width is the printed width of a Code128 barcode of content,
+// including its quiet zones
+func code128Width(content string) (float64, error) {
+	code, err := code128.Encode(content)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode barcode %q: %v", content, err)
+	}
+	return float64(code.Bounds().Dx()+20) * barcodeModuleMM, nil
+}
+
+// qrCodeSize is the printed side length of a QR code of content, including
+// its quiet zone
+func qrCodeSize(content string) (float64, error) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode QR code: %v", err)
+	}
+	return float64(code.Bounds().Dx()+4) * qrModuleMM, nil
+}
+
+// maxQRCodeSize is the printed side length of the largest overlay QR code
+// among orders
+func maxQRCodeSize(orders []PDFOrderData) (float64, error) {
+	largest := 0.0
+	for _, order := range orders {
+		side, err := qrCodeSize(overlayQRPayload(order))
+		if err != nil {
+			return 0, err
+		}
+		largest = math.Max(largest, side)
+	}
+	return largest, nil
+}
+
+// drawCode128 draws a Code128 barcode of content with its top-left corner at
+// x, y and returns its width
+func drawCode128(pdf *gofpdf.Fpdf, content string, x, y float64) (float64, error) {
+	code, err := code128.Encode(content)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode barcode %q: %v", content, err)
+	}
+
+	// Quiet zone of ten modules either side
+	width := float64(code.Bounds().Dx()+20) * barcodeModuleMM
+	pdf.SetFillColor(255, 255, 255)
+	pdf.Rect(x, y, width, barcodeHeightMM, "F")
+
+	drawModules(pdf, code, x+10*barcodeModuleMM, y, barcodeModuleMM, barcodeHeightMM)
+	return width, nil
+}
+
+// drawQRCode draws a QR code of content with its top-left corner at x, y and
+// returns its side length
+func drawQRCode(pdf *gofpdf.Fpdf, content string, x, y float64) (float64, error) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode QR code: %v", err)
+	}
+
+	// Quiet zone of two modules each side
+	side := float64(code.Bounds().Dx()+4) * qrModuleMM
+	pdf.SetFillColor(255, 255, 255)
+	pdf.Rect(x, y, side, side, "F")
+
+	drawModules(pdf, code, x+2*qrModuleMM, y+2*qrModuleMM, qrModuleMM, qrModuleMM)
+	return side, nil
+}
+
+// drawModules fills a black rectangle for every dark module of code. One
+// dimensional codes are a single row stretched to moduleH.
+func drawModules(pdf *gofpdf.Fpdf, code barcode.Barcode, x, y, moduleW, moduleH float64) {
+	pdf.SetFillColor(0, 0, 0)
+	bounds := code.Bounds()
+
+	for row := bounds.Min.Y; row < bounds.Max.Y; row++ {
+		// Merge runs of dark modules into one rectangle
+		runStart := -1
+		for col := bounds.Min.X; col <= bounds.Max.X; col++ {
+			dark := col < bounds.Max.X && isDark(code.At(col, row))
+			switch {
+			case dark && runStart < 0:
+				runStart = col
+			case !dark && runStart >= 0:
+				pdf.Rect(x+float64(runStart-bounds.Min.X)*moduleW, y+float64(row-bounds.Min.Y)*moduleH,
+					float64(col-runStart)*moduleW, moduleH, "F")
+				runStart = -1
+			}
+		}
+	}
+}
+
+func isDark(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r+g+b < 3*0x8000
+}
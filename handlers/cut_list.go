@@ -0,0 +1,309 @@
+// handlers/cut_list.go
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cut list output formats selectable per request
+const (
+	cutListCSV  = "csv"
+	cutListXLSX = "xlsx"
+	cutListPDF  = "pdf"
+)
+
+// sqFtPerSqM converts square metres to square feet
+const sqFtPerSqM = 10.763910417
+
+// cutListGroup is the pieces of one thickness and size to cut
+type cutListGroup struct {
+	Thickness       string
+	Dimension       string
+	ParsedThickness Thickness
+	ParsedDimension Dimension
+	Pieces          int
+	AreaSqM         float64 // total area of all pieces
+	Orders          []string
+	Unmatched       bool // lines whose SKU was not found in the catalog
+}
+
+// OrderList joins the contributing order numbers for output
+func (g cutListGroup) OrderList() string {
+	return strings.Join(g.Orders, ", ")
+}
+
+// requestCutListFormat validates the cutListFormat form field
+func requestCutListFormat(value string) string {
+	switch value {
+	case cutListXLSX, cutListPDF:
+		return value
+	}
+	return cutListCSV
+}
+
+// buildCutList aggregates order lines by thickness and dimension. Groups are
+// sorted by thickness, then largest size first; lines without a catalog
+// match are collected in a final N/A group so nothing is dropped silently.
+func buildCutList(orders []PDFOrderData) []cutListGroup {
+	var groups []cutListGroup
+	index := make(map[string]int)
+	seenOrders := make(map[string]map[string]bool)
+
+	for _, order := range orders {
+		unmatched := order.CatalogSKU == ""
+		key := cutListKey(order)
+		if unmatched {
+			key = "\x00unmatched"
+		}
+
+		i, exists := index[key]
+		if !exists {
+			i = len(groups)
+			index[key] = i
+			seenOrders[key] = make(map[string]bool)
+			group := cutListGroup{
+				Thickness:       order.Thickness,
+				Dimension:       order.Dimension,
+				ParsedThickness: order.ParsedThickness,
+				ParsedDimension: order.ParsedDimension,
+				Unmatched:       unmatched,
+			}
+			if unmatched {
+				group.Thickness = "N/A"
+				group.Dimension = "N/A"
+				group.ParsedThickness = Thickness{}
+				group.ParsedDimension = Dimension{}
+			}
+			groups = append(groups, group)
+		}
+		g := &groups[i]
+
+		quantity := order.Quantity
+		if quantity < 1 {
+			quantity = 1
+		}
+		g.Pieces += quantity
+		g.AreaSqM += g.ParsedDimension.Area("m") * float64(quantity)
+
+		if !seenOrders[key][order.OrderNumber] {
+			seenOrders[key][order.OrderNumber] = true
+			g.Orders = append(g.Orders, order.OrderNumber)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.Unmatched != b.Unmatched {
+			return b.Unmatched
+		}
+		if a.ParsedThickness.MM != b.ParsedThickness.MM {
+			return a.ParsedThickness.MM < b.ParsedThickness.MM
+		}
+		if a.Thickness != b.Thickness {
+			return a.Thickness < b.Thickness
+		}
+		areaA, areaB := a.ParsedDimension.Area("m"), b.ParsedDimension.Area("m")
+		if areaA != areaB {
+			return areaA > areaB
+		}
+		return a.Dimension < b.Dimension
+	})
+
+	return groups
+}
+
+// cutListKey groups lines by parsed thickness and size in millimetres, so
+// "5mm" and "5 mm" or "24x36 in" and "24 x 36 inch" are cut together. Values
+// that did not parse are keyed on the raw catalog text.
+func cutListKey(order PDFOrderData) string {
+	thickness := order.Thickness
+	if order.ParsedThickness.MM > 0 {
+		thickness = formatMeasure(order.ParsedThickness.MM) + "mm"
+	}
+	dimension := order.Dimension
+	if !order.ParsedDimension.IsZero() {
+		dimension = order.ParsedDimension.Format("mm")
+	}
+	return thickness + "\x00" + dimension
+}
+
+// writeCutList writes the cut list in format and returns the file extension
+// used
+func writeCutList(groups []cutListGroup, format, basePath string) (string, error) {
+	switch format {
+	case cutListXLSX:
+		return ".xlsx", writeCutListXLSX(groups, basePath+".xlsx")
+	case cutListPDF:
+		return ".pdf", writeCutListPDF(groups, basePath+".pdf")
+	}
+	return ".csv", writeCutListCSV(groups, basePath+".csv")
+}
+
+var cutListHeaders = []string{"Thickness", "Dimension", "Pieces", "Total Area (m²)", "Total Area (sq ft)", "Orders", "Order Numbers"}
+
+func writeCutListCSV(groups []cutListGroup, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err := writer.Write(cutListHeaders); err != nil {
+		return fmt.Errorf("failed to write CSV header: %v", err)
+	}
+
+	for _, g := range groups {
+		err := writer.Write([]string{
+			g.Thickness,
+			g.Dimension,
+			strconv.Itoa(g.Pieces),
+			fmt.Sprintf("%.3f", g.AreaSqM),
+			fmt.Sprintf("%.2f", g.AreaSqM*sqFtPerSqM),
+			strconv.Itoa(len(g.Orders)),
+			g.OrderList(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write CSV row: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func writeCutListXLSX(groups []cutListGroup, filename string) error {
+	report := xlsxReport{
+		Headers:   cutListHeaders,
+		StatusCol: -1,
+	}
+
+	pieces := 0
+	area := 0.0
+	unmatched := 0
+	for _, g := range groups {
+		report.Rows = append(report.Rows, []interface{}{
+			g.Thickness,
+			g.Dimension,
+			g.Pieces,
+			roundTo3(g.AreaSqM),
+			roundTo2(g.AreaSqM * sqFtPerSqM),
+			len(g.Orders),
+			g.OrderList(),
+		})
+		if g.Unmatched {
+			unmatched += g.Pieces
+			continue
+		}
+		pieces += g.Pieces
+		area += g.AreaSqM
+	}
+
+	report.Summary = []xlsxSummaryItem{
+		{"Sizes", len(groups)},
+		{"Pieces to Cut", pieces},
+		{"Total Area (m²)", roundTo3(area)},
+		{"Total Area (sq ft)", roundTo2(area * sqFtPerSqM)},
+		{"Unmatched Pieces", unmatched},
+	}
+
+	return writeXLSXReport(report, filename)
+}
+
+// writeCutListPDF renders a printable A4 cut list with a subtotal after each
+// thickness
+func writeCutListPDF(groups []cutListGroup, filename string) error {
+	pdf, fonts := newPDFDocument("P", "A4")
+	pdf.SetMargins(10, 12, 10)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		fonts.SetFont(pdf, "", 8)
+		fonts.CellFormat(pdf, 0, 6, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	fonts.SetFont(pdf, "B", 16)
+	fonts.CellFormat(pdf, 0, 10, "Cut List", "", 1, "L", false, 0, "")
+	fonts.SetFont(pdf, "", 9)
+	fonts.CellFormat(pdf, 0, 6, "Generated "+time.Now().Format("02 Jan 2006 15:04"), "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	tr := fonts.Encode
+	headers := []string{"Thickness", "Dimension", "Pieces", tr("Area (m²)"), "Area (sq ft)", "Orders"}
+	widths := []float64{25, 45, 18, 24, 24, 54}
+
+	writeHeader := func() {
+		fonts.SetFont(pdf, "B", 10)
+		pdf.SetFillColor(217, 225, 242)
+		for i, h := range headers {
+			fonts.CellFormat(pdf, widths[i], 8, h, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		fonts.SetFont(pdf, "", 10)
+	}
+	writeHeader()
+
+	writeSubtotal := func(label string, pieces int, area float64) {
+		fonts.SetFont(pdf, "B", 10)
+		pdf.SetFillColor(242, 242, 242)
+		fonts.CellFormat(pdf, widths[0]+widths[1], 7, label, "1", 0, "R", true, 0, "")
+		fonts.CellFormat(pdf, widths[2], 7, strconv.Itoa(pieces), "1", 0, "C", true, 0, "")
+		fonts.CellFormat(pdf, widths[3], 7, fmt.Sprintf("%.3f", area), "1", 0, "R", true, 0, "")
+		fonts.CellFormat(pdf, widths[4], 7, fmt.Sprintf("%.2f", area*sqFtPerSqM), "1", 0, "R", true, 0, "")
+		fonts.CellFormat(pdf, widths[5], 7, "", "1", 1, "L", true, 0, "")
+		fonts.SetFont(pdf, "", 10)
+	}
+
+	totalPieces := 0
+	totalArea := 0.0
+	subPieces := 0
+	subArea := 0.0
+
+	for i, g := range groups {
+		// Keep rows together with the header on a new page
+		if pdf.GetY() > 270 {
+			pdf.AddPage()
+			writeHeader()
+		}
+
+		orders := g.OrderList()
+		if fonts.StringWidth(pdf, orders) > widths[5]-2 {
+			orders = fmt.Sprintf("%d orders", len(g.Orders))
+		}
+
+		fonts.CellFormat(pdf, widths[0], 7, tr(g.Thickness), "1", 0, "L", false, 0, "")
+		fonts.CellFormat(pdf, widths[1], 7, tr(g.Dimension), "1", 0, "L", false, 0, "")
+		fonts.CellFormat(pdf, widths[2], 7, strconv.Itoa(g.Pieces), "1", 0, "C", false, 0, "")
+		fonts.CellFormat(pdf, widths[3], 7, fmt.Sprintf("%.3f", g.AreaSqM), "1", 0, "R", false, 0, "")
+		fonts.CellFormat(pdf, widths[4], 7, fmt.Sprintf("%.2f", g.AreaSqM*sqFtPerSqM), "1", 0, "R", false, 0, "")
+		fonts.CellFormat(pdf, widths[5], 7, orders, "1", 1, "L", false, 0, "")
+
+		if g.Unmatched {
+			continue
+		}
+		subPieces += g.Pieces
+		subArea += g.AreaSqM
+		totalPieces += g.Pieces
+		totalArea += g.AreaSqM
+
+		last := i == len(groups)-1 || groups[i+1].Unmatched || groups[i+1].Thickness != g.Thickness
+		if last {
+			writeSubtotal(tr(g.Thickness)+" total", subPieces, subArea)
+			subPieces = 0
+			subArea = 0
+		}
+	}
+
+	pdf.Ln(2)
+	writeSubtotal("Total to cut", totalPieces, totalArea)
+
+	return pdf.OutputFileAndClose(filename)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import "testing"
+
+func TestCutListGroupsParsedSizes(t *testing.T) {
+	line := func(order, thickness, dimension string) PDFOrderData {
+		parsedThickness, _ := parseThickness(thickness)
+		parsedDimension, _ := parseDimension(dimension)
+		return PDFOrderData{
+			OrderNumber: order, CatalogSKU: "MRC-MR-0530", Quantity: 1,
+			Thickness: thickness, Dimension: dimension,
+			ParsedThickness: parsedThickness, ParsedDimension: parsedDimension,
+		}
+	}
+
+	groups := buildCutList([]PDFOrderData{
+		line("111", "5mm", "24x36 in"),
+		line("222", "5 mm", "24 x 36 Inch"),
+		line("333", "0.5cm", "2 x 3 ft"),
+		line("444", "thick", "large"),
+		line("555", "thick", "large"),
+		line("666", "5mm", "36x24 in"),
+	})
+
+	want := []struct {
+		thickness, dimension, orders string
+		pieces                       int
+	}{
+		{"thick", "large", "444, 555", 2},
+		{"5mm", "24x36 in", "111, 222, 333", 3},
+		{"5mm", "36x24 in", "666", 1},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("got %d groups, want %d: %+v", len(groups), len(want), groups)
+	}
+	for i, w := range want {
+		g := groups[i]
+		if g.Thickness != w.thickness || g.Dimension != w.dimension || g.OrderList() != w.orders || g.Pieces != w.pieces {
+			t.Errorf("group %d = %s %s %d pieces (%s), want %s %s %d pieces (%s)",
+				i, g.Thickness, g.Dimension, g.Pieces, g.OrderList(), w.thickness, w.dimension, w.pieces, w.orders)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// handlers/extraction_diagnostics.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Fallbacks processPDFPages can take when the page text doesn't parse as is
+const (
+	fallbackSplitByOrderPattern = "splitByOrderPattern"
+	fallbackProcessTextSimple   = "processTextSimple"
+)
+
+// ExtractionDiagnostics explains how the orders were read from a PDF, page
+// by page, so a run that finds nothing can be traced to its cause
+type ExtractionDiagnostics struct {
+	Pages     []PageDiagnostics `json:"pages"`
+	Fallbacks []string          `json:"fallbacks,omitempty"`
+	Lines     int               `json:"lines"`
+	Warnings  []string          `json:"warnings,omitempty"`
+
+	// FallbackPairs are the pairs made by processTextSimple, which pairs the
+	// whole document's order numbers and SKUs by position instead of by page
+	FallbackPairs []DiagnosticPair `json:"fallback_pairs,omitempty"`
+}
+
+// PageDiagnostics is what was found on one page and how it was paired
+type PageDiagnostics struct {
+	Page          int              `json:"page"`
+	Type          string           `json:"type"`
+	TextSource    string           `json:"text_source"`
+	OCRConfidence float64          `json:"ocr_confidence,omitempty"`
+	TextLength    int              `json:"text_length"`
+	OrderNumbers  []string         `json:"order_numbers"`
+	SKUs          []string         `json:"skus"`
+	Pairs         []DiagnosticPair `json:"pairs"`
+	Warnings      []string         `json:"warnings,omitempty"`
+}
+
+// DiagnosticPair is an order number and the SKU attributed to it
+type DiagnosticPair struct {
+	OrderNumber string `json:"order_number"`
+	SKU         string `json:"sku"`
+	CatalogSKU  string `json:"catalog_sku,omitempty"`
+	MatchType   string `json:"match_type"`
+	Continued   bool   `json:"continued,omitempty"` // order header is on an earlier page
+}
+
+// diagnosePage records what page's text contains and the lines read from it
+func diagnosePage(page pdfPage, catalog *pdfCatalog, lines []PDFOrderData, current orderContext) PageDiagnostics {
+	d := PageDiagnostics{
+		Page:         page.Number,
+		Type:         page.Type,
+		TextSource:   "Text",
+		TextLength:   len(strings.TrimSpace(page.Text)),
+		OrderNumbers: []string{},
+		SKUs:         []string{},
+		Pairs:        diagnosticPairs(lines),
+	}
+	if page.OCR {
+		d.TextSource = "OCR"
+		d.OCRConfidence = page.OCRConfidence
+	}
+	for _, m := range orderNumberRegex.FindAllStringSubmatch(page.Text, -1) {
+		d.OrderNumbers = append(d.OrderNumbers, m[1])
+	}
+	if ids := findSKUIdentifiers(page.Text, catalog.matcher); ids != nil {
+		d.SKUs = ids
+	}
+
+	headers := make(map[string]bool)
+	for _, orderNumber := range d.OrderNumbers {
+		headers[orderNumber] = true
+	}
+	for i := range d.Pairs {
+		d.Pairs[i].Continued = !headers[d.Pairs[i].OrderNumber]
+	}
+
+	switch {
+	case d.TextLength == 0:
+		d.Warnings = append(d.Warnings, "no text extracted; the page may be a scan and OCR is off or failed")
+	case page.Type == pageTypeLabel:
+		// Labels are not read for SKUs
+	case len(d.OrderNumbers) > 0 && len(d.SKUs) == 0:
+		d.Warnings = append(d.Warnings, fmt.Sprintf("%d order numbers but no SKUs", len(d.OrderNumbers)))
+	case len(d.OrderNumbers) == 0 && len(d.SKUs) > 0 && current.OrderNumber == "":
+		d.Warnings = append(d.Warnings, fmt.Sprintf("%d SKUs but no order number on this or an earlier page", len(d.SKUs)))
+	case len(d.OrderNumbers) > 1 && len(d.OrderNumbers) != len(d.SKUs):
+		d.Warnings = append(d.Warnings, fmt.Sprintf("%d order numbers but %d SKUs", len(d.OrderNumbers), len(d.SKUs)))
+	}
+	if page.Type != pageTypeLabel && len(d.OrderNumbers) > 0 {
+		if leading := skusBeforeFirstOrder(page.Text, catalog); leading > 0 {
+			owner := current.OrderNumber
+			if owner == "" {
+				owner = d.OrderNumbers[0]
+			}
+			d.Warnings = append(d.Warnings, fmt.Sprintf("%d SKUs printed above the first order number, attributed to %s", leading, owner))
+		}
+	}
+	if page.Type != pageTypeLabel && len(d.SKUs) > 0 && len(d.Pairs) < len(d.SKUs) {
+		d.Warnings = append(d.Warnings, fmt.Sprintf("%d of %d SKUs not paired with an order", len(d.SKUs)-len(d.Pairs), len(d.SKUs)))
+	}
+	for _, pair := range d.Pairs {
+		if !matchFound(pair.MatchType) {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("SKU %s not in catalog", pair.SKU))
+		}
+	}
+
+	return d
+}
+
+// skusBeforeFirstOrder counts the SKUs printed above the page's first order
+// header. They could belong to that order or to one from an earlier page.
+func skusBeforeFirstOrder(pageText string, catalog *pdfCatalog) int {
+	first := orderNumberRegex.FindStringIndex(pageText)
+	if first == nil {
+		return 0
+	}
+	count := 0
+	for _, hit := range findSKUHits(pageText, catalog.matcher) {
+		if hit.start < first[0] {
+			count++
+		}
+	}
+	return count
+}
+
+// diagnosticPairs lists the invoice lines behind lines, counting the
+// components of an expanded kit once
+func diagnosticPairs(lines []PDFOrderData) []DiagnosticPair {
+	pairs := []DiagnosticPair{}
+	for i, line := range lines {
+		pair := DiagnosticPair{
+			OrderNumber: line.OrderNumber,
+			SKU:         line.SKUID,
+			CatalogSKU:  line.CatalogSKU,
+			MatchType:   line.MatchType,
+		}
+		if line.KitSKU != "" {
+			if i > 0 && lines[i-1].KitSKU == line.KitSKU && lines[i-1].OrderNumber == line.OrderNumber {
+				continue
+			}
+			pair.SKU = line.KitSKU
+			pair.CatalogSKU = ""
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+// writeDiagnosticsJSON saves the diagnostics for download
+func writeDiagnosticsJSON(diag *ExtractionDiagnostics, filename string) error {
+	data, err := json.MarshalIndent(diag, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode diagnostics: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write diagnostics: %v", err)
+	}
+	return nil
+}
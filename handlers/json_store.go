@@ -0,0 +1,144 @@
+// handlers/json_store.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// jsonStore is a server-side table of records keyed by key, persisted as a
+// JSON array sorted by key. It is loaded on first use.
+type jsonStore[T any] struct {
+	mu     sync.Mutex
+	path   string
+	name   string // for error messages, e.g. "alias store"
+	key    func(T) string
+	loaded bool
+	items  map[string]T
+}
+
+func newJSONStore[T any](path, name string, key func(T) string) *jsonStore[T] {
+	return &jsonStore[T]{path: path, name: name, key: key}
+}
+
+func (s *jsonStore[T]) load() error {
+	if s.loaded {
+		return nil
+	}
+
+	var list []T
+	if _, err := readJSONFile(s.path, &list, s.name); err != nil {
+		return err
+	}
+	s.items = make(map[string]T, len(list))
+	for _, item := range list {
+		s.items[s.key(item)] = item
+	}
+
+	s.loaded = true
+	return nil
+}
+
+func (s *jsonStore[T]) sortedLocked() []T {
+	list := make([]T, 0, len(s.items))
+	for _, item := range s.items {
+		list = append(list, item)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return s.key(list[i]) < s.key(list[j])
+	})
+	return list
+}
+
+// List returns all records sorted by key
+func (s *jsonStore[T]) List() ([]T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s.sortedLocked(), nil
+}
+
+// Get returns the record stored under key
+func (s *jsonStore[T]) Get(key string) (T, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var item T
+	if err := s.load(); err != nil {
+		return item, false, err
+	}
+	item, found := s.items[key]
+	return item, found, nil
+}
+
+// Put adds or replaces a record
+func (s *jsonStore[T]) Put(item T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	s.items[s.key(item)] = item
+	return writeJSONFile(s.path, s.sortedLocked(), s.name)
+}
+
+// Delete removes a record, reporting whether it existed
+func (s *jsonStore[T]) Delete(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return false, err
+	}
+
+	if _, exists := s.items[key]; !exists {
+		return false, nil
+	}
+	delete(s.items, key)
+	return true, writeJSONFile(s.path, s.sortedLocked(), s.name)
+}
+
+// readJSONFile decodes the JSON file at path into v, reporting whether the
+// file exists. A missing file is not an error.
+func readJSONFile(path string, v any, name string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read %s: %v", name, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return true, fmt.Errorf("failed to parse %s: %v", name, err)
+	}
+	return true, nil
+}
+
+// writeJSONFile saves v as indented JSON, creating the directory
+func writeJSONFile(path string, v any, name string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %v", name, err)
+	}
+
+	// Write to a temp file first so a crash can't truncate the store
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return os.Rename(tempPath, path)
+}
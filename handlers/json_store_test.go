@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "aliases.json")
+	key := func(a SKUAlias) string { return a.Alias }
+
+	store := &aliasStore{newJSONStore(path, "alias store", key)}
+	for _, alias := range []SKUAlias{{Alias: "B0TEST2", SKU: "MRC-MR-0531"}, {Alias: "B0TEST1", SKU: "MRC-MR-0530"}} {
+		if err := store.Put(alias); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if found, err := store.Delete("missing"); found || err != nil {
+		t.Errorf("Delete(missing) = %v, %v", found, err)
+	}
+
+	// A fresh store reads what the first one wrote
+	reloaded := &aliasStore{newJSONStore(path, "alias store", key)}
+	list, err := reloaded.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Alias != "B0TEST1" || list[1].Alias != "B0TEST2" {
+		t.Fatalf("reloaded %+v, want both aliases sorted", list)
+	}
+	if list[0].UpdatedAt.IsZero() {
+		t.Error("Put did not stamp UpdatedAt")
+	}
+
+	if found, err := reloaded.Delete("B0TEST1"); !found || err != nil {
+		t.Errorf("Delete(B0TEST1) = %v, %v", found, err)
+	}
+	if _, found, _ := reloaded.Get("B0TEST1"); found {
+		t.Error("deleted alias still stored")
+	}
+}
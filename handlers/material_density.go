@@ -0,0 +1,80 @@
+// handlers/material_density.go
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const densitiesSheetName = "Densities"
+
+// materialDensities maps thickness in mm to material density in kg/m³
+type materialDensities map[float64]float64
+
+// For returns the density configured for thickness t
+func (m materialDensities) For(t Thickness) (float64, bool) {
+	for mm, density := range m {
+		if math.Abs(mm-t.MM) < 0.001 {
+			return density, true
+		}
+	}
+	return 0, false
+}
+
+// loadCatalogDensities reads the optional "Densities" sheet (Thickness,
+// Density) from an uploaded catalog workbook
+func loadCatalogDensities(filename string) (materialDensities, error) {
+	densities := make(materialDensities)
+
+	file, err := excelize.OpenFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Excel file: %v", err)
+	}
+	defer file.Close()
+
+	if idx, _ := file.GetSheetIndex(densitiesSheetName); idx < 0 {
+		return densities, nil
+	}
+
+	rows, err := file.GetRows(densitiesSheetName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s sheet: %v", densitiesSheetName, err)
+	}
+
+	// Skip header row
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) < 2 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+
+		thickness, err := parseThickness(row[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s sheet row %d: %v", densitiesSheetName, i+1, err)
+		}
+		density, err := parseDensity(row[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s sheet row %d: %v", densitiesSheetName, i+1, err)
+		}
+
+		densities[thickness.MM] = density
+	}
+
+	return densities, nil
+}
+
+// catalogWeight returns a row's weight in kg: the parsed Weight cell when
+// present, otherwise a value derived from the row's area, thickness and the
+// density for that thickness
+func catalogWeight(weightCell string, t Thickness, d Dimension, densities materialDensities) (weight float64, derived bool, err error) {
+	if strings.TrimSpace(weightCell) != "" {
+		weight, err = parseWeight(weightCell)
+		return weight, false, err
+	}
+
+	weight, derived = deriveWeight(t, d, densities)
+	return weight, derived, nil
+}
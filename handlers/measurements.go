@@ -0,0 +1,281 @@
+// handlers/measurements.go
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Millimetres per unit for the length units accepted in the catalog
+var unitToMM = map[string]float64{
+	"mm": 1,
+	"cm": 10,
+	"m":  1000,
+	"in": 25.4,
+	"ft": 304.8,
+}
+
+// Dimension display modes selectable per request
+const (
+	dimensionUnitOriginal = "original"
+	dimensionUnitInches   = "in"
+	dimensionUnitCM       = "cm"
+	dimensionUnitBoth     = "both"
+)
+
+// Thickness is a parsed catalog thickness
+type Thickness struct {
+	MM float64
+}
+
+// Dimension is a parsed catalog "W x H" size in its original unit
+type Dimension struct {
+	Width  float64
+	Height float64
+	Unit   string // mm, cm, m, in or ft
+}
+
+// IsZero reports whether the dimension was never parsed
+func (d Dimension) IsZero() bool {
+	return d.Unit == ""
+}
+
+// In returns width and height converted to unit
+func (d Dimension) In(unit string) (float64, float64) {
+	if d.IsZero() {
+		return 0, 0
+	}
+	factor := unitToMM[d.Unit] / unitToMM[unit]
+	return d.Width * factor, d.Height * factor
+}
+
+// Area returns the area in square units of unit
+func (d Dimension) Area(unit string) float64 {
+	w, h := d.In(unit)
+	return w * h
+}
+
+// Format renders the dimension as "W x H unit" in unit
+func (d Dimension) Format(unit string) string {
+	w, h := d.In(unit)
+	return fmt.Sprintf("%s x %s %s", formatMeasure(w), formatMeasure(h), unit)
+}
+
+// formatMeasure prints v to at most one decimal place
+func formatMeasure(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
+
+var (
+	unitPattern      = `(mm|millimet(?:er|re)s?|cm|centimet(?:er|re)s?|m|met(?:er|re)s?|in|inch|inches|"|''|ft|feet|foot|')`
+	thicknessRegex   = regexp.MustCompile(`(?i)^\s*(\d+(?:\.\d+)?)\s*` + unitPattern + `?\s*$`)
+	dimensionRegex   = regexp.MustCompile(`(?i)^\s*(\d+(?:\.\d+)?)\s*` + unitPattern + `?\s*[x×*]\s*(\d+(?:\.\d+)?)\s*` + unitPattern + `?\s*$`)
+	canonicalUnitMap = map[string]string{
+		"mm": "mm", "millimeter": "mm", "millimeters": "mm", "millimetre": "mm", "millimetres": "mm",
+		"cm": "cm", "centimeter": "cm", "centimeters": "cm", "centimetre": "cm", "centimetres": "cm",
+		"m": "m", "meter": "m", "meters": "m", "metre": "m", "metres": "m",
+		"in": "in", "inch": "in", "inches": "in", `"`: "in", "''": "in",
+		"ft": "ft", "feet": "ft", "foot": "ft", "'": "ft",
+	}
+)
+
+func canonicalUnit(unit string) string {
+	return canonicalUnitMap[strings.ToLower(unit)]
+}
+
+// parseThickness parses values such as "2mm", "2 mm" or "1.5". A missing
+// unit means millimetres.
+func parseThickness(value string) (Thickness, error) {
+	m := thicknessRegex.FindStringSubmatch(value)
+	if m == nil {
+		return Thickness{}, fmt.Errorf("unrecognised thickness %q", value)
+	}
+
+	v, _ := strconv.ParseFloat(m[1], 64)
+	unit := "mm"
+	if m[2] != "" {
+		unit = canonicalUnit(m[2])
+	}
+
+	return Thickness{MM: v * unitToMM[unit]}, nil
+}
+
+// parseDimension parses values such as "24 x 48 Inch", "24x48 in" or
+// "61 × 122 cm". The unit may be given once or on both sides but is required.
+func parseDimension(value string) (Dimension, error) {
+	m := dimensionRegex.FindStringSubmatch(value)
+	if m == nil {
+		return Dimension{}, fmt.Errorf("unrecognised dimension %q", value)
+	}
+
+	width, _ := strconv.ParseFloat(m[1], 64)
+	height, _ := strconv.ParseFloat(m[3], 64)
+	widthUnit := canonicalUnit(m[2])
+	heightUnit := canonicalUnit(m[4])
+
+	switch {
+	case widthUnit == "" && heightUnit == "":
+		return Dimension{}, fmt.Errorf("dimension %q has no unit", value)
+	case widthUnit == "":
+		widthUnit = heightUnit
+	case heightUnit == "":
+		heightUnit = widthUnit
+	}
+
+	// Mixed units are stored in the height unit
+	if widthUnit != heightUnit {
+		width = width * unitToMM[widthUnit] / unitToMM[heightUnit]
+	}
+
+	return Dimension{Width: width, Height: height, Unit: heightUnit}, nil
+}
+
+// formatDimension renders a catalog dimension for output. original is
+// returned unchanged for the "original" mode or when parsing failed.
+func formatDimension(original string, d Dimension, mode string) string {
+	if d.IsZero() {
+		return original
+	}
+
+	switch mode {
+	case dimensionUnitInches:
+		return d.Format("in")
+	case dimensionUnitCM:
+		return d.Format("cm")
+	case dimensionUnitBoth:
+		return d.Format("in") + " (" + d.Format("cm") + ")"
+	}
+	return original
+}
+
+// requestDimensionUnit validates the dimensionUnit form field
+func requestDimensionUnit(value string) string {
+	switch value {
+	case dimensionUnitInches, dimensionUnitCM, dimensionUnitBoth:
+		return value
+	}
+	return dimensionUnitOriginal
+}
+
+// parseCatalogMeasurements parses a catalog row's thickness and dimension.
+// Empty cells are allowed; anything else must parse.
+func parseCatalogMeasurements(thickness, dimension string) (Thickness, Dimension, error) {
+	var t Thickness
+	var d Dimension
+	var err error
+
+	if thickness != "" {
+		if t, err = parseThickness(thickness); err != nil {
+			return t, d, err
+		}
+	}
+	if dimension != "" {
+		if d, err = parseDimension(dimension); err != nil {
+			return t, d, err
+		}
+	}
+
+	return t, d, nil
+}
+
+// Kilograms per unit for the weight units accepted in the catalog
+var unitToKG = map[string]float64{
+	"kg": 1,
+	"g":  0.001,
+	"lb": 0.45359237,
+	"oz": 0.028349523125,
+}
+
+var (
+	weightRegex      = regexp.MustCompile(`(?i)^\s*(\d+(?:\.\d+)?)\s*(kg|kgs|kilo(?:gram|gramme)?s?|g|gm|gms|grams?|grammes?|lb|lbs|pounds?|oz|ounces?)?\s*$`)
+	weightUnitPrefix = map[string]string{"k": "kg", "g": "g", "l": "lb", "p": "lb", "o": "oz"}
+)
+
+// parseWeight parses values such as "0.36", "0.36 kg", "360 g" or "0.8 lb"
+// and returns kilograms. A missing unit means kilograms.
+func parseWeight(value string) (float64, error) {
+	m := weightRegex.FindStringSubmatch(value)
+	if m == nil {
+		return 0, fmt.Errorf("unrecognised weight %q", value)
+	}
+
+	v, _ := strconv.ParseFloat(m[1], 64)
+	unit := "kg"
+	if m[2] != "" {
+		unit = weightUnitPrefix[strings.ToLower(m[2][:1])]
+	}
+
+	return v * unitToKG[unit], nil
+}
+
+var densityRegex = regexp.MustCompile(`(?i)^\s*(\d+(?:\.\d+)?)\s*(kg\s*/\s*m(?:3|³)|g\s*/\s*cm(?:3|³)|g\s*/\s*cc)?\s*$`)
+
+// parseDensity parses values such as "1200", "1200 kg/m3" or "1.2 g/cm³"
+// and returns kg/m³. A missing unit means kg/m³.
+func parseDensity(value string) (float64, error) {
+	m := densityRegex.FindStringSubmatch(value)
+	if m == nil {
+		return 0, fmt.Errorf("unrecognised density %q", value)
+	}
+
+	v, _ := strconv.ParseFloat(m[1], 64)
+	if m[2] != "" && strings.HasPrefix(strings.ToLower(m[2]), "g") {
+		v *= 1000
+	}
+	return v, nil
+}
+
+// deriveWeight estimates a piece's weight in kg from its area, thickness and
+// the material density for that thickness. ok is false when any input is
+// missing.
+func deriveWeight(t Thickness, d Dimension, densities materialDensities) (float64, bool) {
+	if t.MM <= 0 || d.IsZero() {
+		return 0, false
+	}
+
+	density, found := densities.For(t)
+	if !found {
+		return 0, false
+	}
+
+	volume := d.Area("m") * t.MM / 1000 // m³
+	return volume * density, true
+}
+
+// PackedSize is a shipping box size in centimetres
+type PackedSize struct {
+	LengthCM float64
+	WidthCM  float64
+	HeightCM float64
+}
+
+// IsZero reports whether no packed size is known
+func (p PackedSize) IsZero() bool {
+	return p.LengthCM == 0 && p.WidthCM == 0 && p.HeightCM == 0
+}
+
+// VolumeCM3 returns the box volume in cubic centimetres
+func (p PackedSize) VolumeCM3() float64 {
+	return p.LengthCM * p.WidthCM * p.HeightCM
+}
+
+var packedSizeRegex = regexp.MustCompile(`(?i)^\s*(\d+(?:\.\d+)?)\s*[x×*]\s*(\d+(?:\.\d+)?)\s*[x×*]\s*(\d+(?:\.\d+)?)\s*` + unitPattern + `\s*$`)
+
+// parsePackedSize parses an "L x W x H unit" box size such as
+// "62 x 10 x 10 cm" or "24x4x4 in"
+func parsePackedSize(value string) (PackedSize, error) {
+	m := packedSizeRegex.FindStringSubmatch(value)
+	if m == nil {
+		return PackedSize{}, fmt.Errorf("unrecognised packed dimensions %q (expected L x W x H with unit)", value)
+	}
+
+	factor := unitToMM[canonicalUnit(m[4])] / unitToMM["cm"]
+	l, _ := strconv.ParseFloat(m[1], 64)
+	w, _ := strconv.ParseFloat(m[2], 64)
+	h, _ := strconv.ParseFloat(m[3], 64)
+
+	return PackedSize{LengthCM: l * factor, WidthCM: w * factor, HeightCM: h * factor}, nil
+}
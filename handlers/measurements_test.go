@@ -0,0 +1,187 @@
+package handlers
+
+import "testing"
+
+func TestParseDimension(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Dimension
+		fails bool
+	}{
+		{value: "24x36 in", want: Dimension{24, 36, "in"}},
+		{value: "24 x 48 Inch", want: Dimension{24, 48, "in"}},
+		{value: "61 × 122 cm", want: Dimension{61, 122, "cm"}},
+		{value: "2.5*4 ft", want: Dimension{2.5, 4, "ft"}},
+		{value: `24" x 36"`, want: Dimension{24, 36, "in"}},
+		{value: "600mm x 900", want: Dimension{600, 900, "mm"}},
+		{value: "  1 x 2 metres ", want: Dimension{1, 2, "m"}},
+		{value: "2 ft x 24 in", want: Dimension{24, 24, "in"}}, // stored in the height unit
+		{value: "24x36", fails: true},                          // no unit
+		{value: "24 in", fails: true},
+		{value: "24x36 yards", fails: true},
+		{value: "axb cm", fails: true},
+		{value: "", fails: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDimension(tt.value)
+		switch {
+		case tt.fails && err == nil:
+			t.Errorf("parseDimension(%q) = %+v, want an error", tt.value, got)
+		case !tt.fails && err != nil:
+			t.Errorf("parseDimension(%q): %v", tt.value, err)
+		case !tt.fails && (!closeTo(got.Width, tt.want.Width) || !closeTo(got.Height, tt.want.Height) || got.Unit != tt.want.Unit):
+			t.Errorf("parseDimension(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseThickness(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64 // mm
+		fails bool
+	}{
+		{value: "5mm", want: 5},
+		{value: "2 mm", want: 2},
+		{value: "1.5", want: 1.5}, // mm by default
+		{value: "0.5 cm", want: 5},
+		{value: "0.25in", want: 6.35},
+		{value: "6 Millimetres", want: 6},
+		{value: "thick", fails: true},
+		{value: "5 mm x 2", fails: true},
+		{value: "-5mm", fails: true},
+		{value: "", fails: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseThickness(tt.value)
+		switch {
+		case tt.fails && err == nil:
+			t.Errorf("parseThickness(%q) = %+v, want an error", tt.value, got)
+		case !tt.fails && err != nil:
+			t.Errorf("parseThickness(%q): %v", tt.value, err)
+		case !tt.fails && !closeTo(got.MM, tt.want):
+			t.Errorf("parseThickness(%q) = %v mm, want %v", tt.value, got.MM, tt.want)
+		}
+	}
+}
+
+func TestParseWeight(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64 // kg
+		fails bool
+	}{
+		{value: "0.36", want: 0.36}, // kg by default
+		{value: "0.36 kg", want: 0.36},
+		{value: "2 Kgs", want: 2},
+		{value: "360 g", want: 0.36},
+		{value: "360gms", want: 0.36},
+		{value: "1 lb", want: 0.45359237},
+		{value: "2 pounds", want: 0.90718474},
+		{value: "16 oz", want: 0.45359237},
+		{value: "heavy", fails: true},
+		{value: "2 tonnes", fails: true},
+		{value: "1,5 kg", fails: true},
+		{value: "", fails: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseWeight(tt.value)
+		switch {
+		case tt.fails && err == nil:
+			t.Errorf("parseWeight(%q) = %v, want an error", tt.value, got)
+		case !tt.fails && err != nil:
+			t.Errorf("parseWeight(%q): %v", tt.value, err)
+		case !tt.fails && !closeTo(got, tt.want):
+			t.Errorf("parseWeight(%q) = %v kg, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseDensity(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64 // kg/m³
+		fails bool
+	}{
+		{value: "1200", want: 1200}, // kg/m³ by default
+		{value: "1200 kg/m3", want: 1200},
+		{value: "1200 kg / m³", want: 1200},
+		{value: "1.2 g/cm³", want: 1200},
+		{value: "1.2 g/cc", want: 1200},
+		{value: "dense", fails: true},
+		{value: "1200 lb/ft3", fails: true},
+		{value: "", fails: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDensity(tt.value)
+		switch {
+		case tt.fails && err == nil:
+			t.Errorf("parseDensity(%q) = %v, want an error", tt.value, got)
+		case !tt.fails && err != nil:
+			t.Errorf("parseDensity(%q): %v", tt.value, err)
+		case !tt.fails && !closeTo(got, tt.want):
+			t.Errorf("parseDensity(%q) = %v kg/m³, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDeriveWeight(t *testing.T) {
+	mat, _ := parseDimension("24x36 in")
+	densities := materialDensities{5: 1200}
+
+	// 0.6096 m x 0.9144 m x 0.005 m = 0.0027870912 m³, at 1200 kg/m³
+	weight, ok := deriveWeight(Thickness{MM: 5}, mat, densities)
+	if !ok || !closeTo(weight, 3.34450944) {
+		t.Errorf("deriveWeight = %v, %v; want 3.34450944 kg", weight, ok)
+	}
+
+	missing := []struct {
+		name      string
+		thickness Thickness
+		dimension Dimension
+		densities materialDensities
+	}{
+		{"no thickness", Thickness{}, mat, densities},
+		{"no dimension", Thickness{MM: 5}, Dimension{}, densities},
+		{"no density for thickness", Thickness{MM: 3}, mat, densities},
+		{"no densities", Thickness{MM: 5}, mat, nil},
+	}
+	for _, tt := range missing {
+		if weight, ok := deriveWeight(tt.thickness, tt.dimension, tt.densities); ok {
+			t.Errorf("%s: deriveWeight = %v, want no weight", tt.name, weight)
+		}
+	}
+}
+
+func TestParsePackedSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  PackedSize
+		fails bool
+	}{
+		{value: "62 x 10 x 10 cm", want: PackedSize{62, 10, 10}},
+		{value: "24x4x4 in", want: PackedSize{60.96, 10.16, 10.16}},
+		{value: "620×100×100 mm", want: PackedSize{62, 10, 10}},
+		{value: "0.62*0.1*0.1 m", want: PackedSize{62, 10, 10}},
+		{value: "62 x 10 x 10", fails: true}, // no unit
+		{value: "62 x 10 cm", fails: true},
+		{value: "a x b x c cm", fails: true},
+		{value: "", fails: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePackedSize(tt.value)
+		switch {
+		case tt.fails && err == nil:
+			t.Errorf("parsePackedSize(%q) = %+v, want an error", tt.value, got)
+		case !tt.fails && err != nil:
+			t.Errorf("parsePackedSize(%q): %v", tt.value, err)
+		case !tt.fails && (!closeTo(got.LengthCM, tt.want.LengthCM) || !closeTo(got.WidthCM, tt.want.WidthCM) || !closeTo(got.HeightCM, tt.want.HeightCM)):
+			t.Errorf("parsePackedSize(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
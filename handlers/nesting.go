@@ -0,0 +1,456 @@
+// handlers/nesting.go
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const stockSheetName = "Stock"
+
+// stockSize is the sheet or roll mats of one thickness are cut from
+type stockSize struct {
+	Label    string  // size as written on the Stock sheet
+	WidthMM  float64 // across the sheet or roll
+	LengthMM float64 // along the sheet or roll
+	Roll     bool    // only the length actually used is consumed
+}
+
+// stockSizes maps thickness in mm to its stock size
+type stockSizes map[float64]stockSize
+
+// For returns the stock configured for thickness t
+func (s stockSizes) For(t Thickness) (stockSize, bool) {
+	for mm, stock := range s {
+		if sameThickness(mm, t.MM) {
+			return stock, true
+		}
+	}
+	return stockSize{}, false
+}
+
+// sameThickness compares thicknesses in mm, allowing for unit conversion
+func sameThickness(a, b float64) bool {
+	return math.Abs(a-b) < 0.001
+}
+
+// loadCatalogStock reads the optional "Stock" sheet (Thickness, Stock Size,
+// Type) from an uploaded catalog workbook. Type is "Sheet" or "Roll" and
+// defaults to Sheet; a roll's size is its width by its full length.
+func loadCatalogStock(filename string) (stockSizes, error) {
+	stock := make(stockSizes)
+
+	file, err := excelize.OpenFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Excel file: %v", err)
+	}
+	defer file.Close()
+
+	if idx, _ := file.GetSheetIndex(stockSheetName); idx < 0 {
+		return stock, nil
+	}
+
+	rows, err := file.GetRows(stockSheetName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s sheet: %v", stockSheetName, err)
+	}
+
+	// Skip header row
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) < 2 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+
+		thickness, err := parseThickness(row[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s sheet row %d: %v", stockSheetName, i+1, err)
+		}
+		size, err := parseDimension(row[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s sheet row %d: %v", stockSheetName, i+1, err)
+		}
+		if _, exists := stock.For(thickness); exists {
+			return nil, fmt.Errorf("%s sheet row %d: duplicate thickness %q", stockSheetName, i+1, strings.TrimSpace(row[0]))
+		}
+
+		roll := false
+		if len(row) > 2 {
+			switch strings.ToLower(strings.TrimSpace(row[2])) {
+			case "", "sheet":
+			case "roll":
+				roll = true
+			default:
+				return nil, fmt.Errorf("%s sheet row %d: type must be Sheet or Roll, got %q", stockSheetName, i+1, row[2])
+			}
+		}
+
+		width, length := size.In("mm")
+		stock[thickness.MM] = stockSize{
+			Label:    strings.TrimSpace(row[1]),
+			WidthMM:  width,
+			LengthMM: length,
+			Roll:     roll,
+		}
+	}
+
+	return stock, nil
+}
+
+// nestTolerance absorbs rounding from unit conversion when a piece exactly
+// matches the space left for it, in mm
+const nestTolerance = 0.01
+
+// nestRect is an axis-aligned rectangle on a stock sheet, in mm
+type nestRect struct {
+	X, Y, W, H float64
+}
+
+// nestPlacement is one piece positioned on a sheet
+type nestPlacement struct {
+	nestRect
+	Label   string
+	Rotated bool
+}
+
+// nestSheet is one stock sheet or length of roll and the pieces cut from it
+type nestSheet struct {
+	Thickness  string
+	ThickMM    float64
+	Stock      stockSize
+	Placements []nestPlacement
+	free       []nestRect
+}
+
+// UsedLengthMM is the length of stock consumed: the whole sheet, or the
+// roll up to the far edge of the last piece
+func (s *nestSheet) UsedLengthMM() float64 {
+	if !s.Stock.Roll {
+		return s.Stock.LengthMM
+	}
+	used := 0.0
+	for _, p := range s.Placements {
+		used = math.Max(used, p.Y+p.H)
+	}
+	return used
+}
+
+// WastePercent is the share of consumed stock area not covered by pieces
+func (s *nestSheet) WastePercent() float64 {
+	total := s.Stock.WidthMM * s.UsedLengthMM()
+	if total == 0 {
+		return 0
+	}
+	used := 0.0
+	for _, p := range s.Placements {
+		used += p.W * p.H
+	}
+	return (1 - used/total) * 100
+}
+
+// nestUnplaced is a group of pieces that could not be nested
+type nestUnplaced struct {
+	Thickness string
+	Dimension string
+	Pieces    int
+	Reason    string
+}
+
+// nestingPlan is the full cutting plan for a set of orders
+type nestingPlan struct {
+	Sheets   []*nestSheet
+	Unplaced []nestUnplaced
+}
+
+// nestPiece is one rectangle to cut
+type nestPiece struct {
+	Thickness string
+	ThickMM   float64
+	Label     string
+	W, H      float64
+}
+
+// planNesting packs the pieces of the cut list onto stock sheets with a
+// guillotine heuristic: pieces are placed largest first into the free
+// rectangle that leaves the least area over (rotating by 90° when that fits
+// better), and the remainder is split along the shorter leftover axis so
+// every cut runs edge to edge. A new sheet is opened only when a piece fits
+// none of the open sheets of its thickness.
+func planNesting(groups []cutListGroup, stock stockSizes) nestingPlan {
+	var plan nestingPlan
+	var pieces []nestPiece
+
+	for _, g := range groups {
+		switch {
+		case g.Unmatched:
+			plan.Unplaced = append(plan.Unplaced, nestUnplaced{g.Thickness, g.Dimension, g.Pieces, "SKU not in catalog"})
+			continue
+		case g.ParsedThickness.MM <= 0 || g.ParsedDimension.IsZero():
+			plan.Unplaced = append(plan.Unplaced, nestUnplaced{g.Thickness, g.Dimension, g.Pieces, "no thickness or dimension"})
+			continue
+		}
+
+		sheet, found := stock.For(g.ParsedThickness)
+		if !found {
+			plan.Unplaced = append(plan.Unplaced, nestUnplaced{g.Thickness, g.Dimension, g.Pieces, "no stock size for thickness"})
+			continue
+		}
+
+		w, h := g.ParsedDimension.In("mm")
+		if !fitsEitherWay(w, h, sheet.WidthMM, sheet.LengthMM) {
+			plan.Unplaced = append(plan.Unplaced, nestUnplaced{g.Thickness, g.Dimension, g.Pieces, "larger than stock " + sheet.Label})
+			continue
+		}
+
+		for i := 0; i < g.Pieces; i++ {
+			pieces = append(pieces, nestPiece{
+				Thickness: g.Thickness,
+				ThickMM:   g.ParsedThickness.MM,
+				Label:     g.Dimension,
+				W:         w,
+				H:         h,
+			})
+		}
+	}
+
+	sort.SliceStable(pieces, func(i, j int) bool {
+		a, b := pieces[i], pieces[j]
+		if a.ThickMM != b.ThickMM {
+			return a.ThickMM < b.ThickMM
+		}
+		return a.W*a.H > b.W*b.H
+	})
+
+	for _, piece := range pieces {
+		placed := false
+		for _, sheet := range plan.Sheets {
+			if sameThickness(sheet.ThickMM, piece.ThickMM) && sheet.place(piece) {
+				placed = true
+				break
+			}
+		}
+		if placed {
+			continue
+		}
+
+		stockSheet, _ := stock.For(Thickness{MM: piece.ThickMM})
+		sheet := &nestSheet{
+			Thickness: piece.Thickness,
+			ThickMM:   piece.ThickMM,
+			Stock:     stockSheet,
+			free:      []nestRect{{0, 0, stockSheet.WidthMM, stockSheet.LengthMM}},
+		}
+		sheet.place(piece) // always fits an empty sheet; checked above
+		plan.Sheets = append(plan.Sheets, sheet)
+	}
+
+	return plan
+}
+
+func fitsEitherWay(w, h, sheetW, sheetH float64) bool {
+	return (w <= sheetW+nestTolerance && h <= sheetH+nestTolerance) ||
+		(h <= sheetW+nestTolerance && w <= sheetH+nestTolerance)
+}
+
+// place puts piece into the best-fitting free rectangle and reports whether
+// it fitted
+func (s *nestSheet) place(piece nestPiece) bool {
+	best := -1
+	bestRotated := false
+	bestLeftover := math.Inf(1)
+	bestShortSide := math.Inf(1)
+
+	for i, fr := range s.free {
+		for _, rotated := range []bool{false, true} {
+			w, h := piece.W, piece.H
+			if rotated {
+				w, h = h, w
+			}
+			if w > fr.W+nestTolerance || h > fr.H+nestTolerance {
+				continue
+			}
+
+			leftover := fr.W*fr.H - w*h
+			shortSide := math.Min(fr.W-w, fr.H-h)
+			// On a roll prefer positions nearer the start to keep the used
+			// length short
+			if s.Stock.Roll {
+				leftover += fr.Y * s.Stock.WidthMM
+			}
+			if leftover < bestLeftover || (leftover == bestLeftover && shortSide < bestShortSide) {
+				best, bestRotated = i, rotated
+				bestLeftover, bestShortSide = leftover, shortSide
+			}
+		}
+	}
+	if best < 0 {
+		return false
+	}
+
+	fr := s.free[best]
+	w, h := piece.W, piece.H
+	if bestRotated {
+		w, h = h, w
+	}
+	w, h = math.Min(w, fr.W), math.Min(h, fr.H)
+	s.Placements = append(s.Placements, nestPlacement{
+		nestRect: nestRect{fr.X, fr.Y, w, h},
+		Label:    piece.Label,
+		Rotated:  bestRotated,
+	})
+
+	// Guillotine split of the remainder along the shorter leftover axis
+	var right, below nestRect
+	if fr.W-w < fr.H-h {
+		right = nestRect{fr.X + w, fr.Y, fr.W - w, h}
+		below = nestRect{fr.X, fr.Y + h, fr.W, fr.H - h}
+	} else {
+		right = nestRect{fr.X + w, fr.Y, fr.W - w, fr.H}
+		below = nestRect{fr.X, fr.Y + h, w, fr.H - h}
+	}
+
+	s.free = append(s.free[:best], s.free[best+1:]...)
+	for _, r := range []nestRect{right, below} {
+		if r.W > nestTolerance && r.H > nestTolerance {
+			s.free = append(s.free, r)
+		}
+	}
+
+	return true
+}
+
+// writeNestingPDF renders a summary page with sheet counts and waste per
+// thickness, followed by one scaled layout diagram per sheet
+func writeNestingPDF(plan nestingPlan, filename string) error {
+	pdf, fonts := newPDFDocument("L", "A4")
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(true, 12)
+	tr := fonts.Encode
+
+	pdf.AddPage()
+	fonts.SetFont(pdf, "B", 16)
+	fonts.CellFormat(pdf, 0, 10, "Nesting Plan", "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	// Summary per thickness
+	type thicknessTotals struct {
+		thickness string
+		stock     string
+		sheets    int
+		pieces    int
+		usedArea  float64
+		stockArea float64
+	}
+	var totals []*thicknessTotals
+	byThickness := make(map[float64]*thicknessTotals)
+	for _, sheet := range plan.Sheets {
+		t, exists := byThickness[sheet.ThickMM]
+		if !exists {
+			kind := "sheet"
+			if sheet.Stock.Roll {
+				kind = "roll"
+			}
+			t = &thicknessTotals{thickness: sheet.Thickness, stock: sheet.Stock.Label + " " + kind}
+			byThickness[sheet.ThickMM] = t
+			totals = append(totals, t)
+		}
+		t.sheets++
+		t.pieces += len(sheet.Placements)
+		t.stockArea += sheet.Stock.WidthMM * sheet.UsedLengthMM()
+		for _, p := range sheet.Placements {
+			t.usedArea += p.W * p.H
+		}
+	}
+
+	headers := []string{"Thickness", "Stock", "Sheets", "Pieces", "Stock Used (m²)", "Waste %"}
+	widths := []float64{35, 70, 25, 25, 40, 30}
+	fonts.SetFont(pdf, "B", 10)
+	pdf.SetFillColor(217, 225, 242)
+	for i, h := range headers {
+		fonts.CellFormat(pdf, widths[i], 8, tr(h), "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+	fonts.SetFont(pdf, "", 10)
+	for _, t := range totals {
+		waste := 0.0
+		if t.stockArea > 0 {
+			waste = (1 - t.usedArea/t.stockArea) * 100
+		}
+		fonts.CellFormat(pdf, widths[0], 7, tr(t.thickness), "1", 0, "L", false, 0, "")
+		fonts.CellFormat(pdf, widths[1], 7, tr(t.stock), "1", 0, "L", false, 0, "")
+		fonts.CellFormat(pdf, widths[2], 7, fmt.Sprintf("%d", t.sheets), "1", 0, "C", false, 0, "")
+		fonts.CellFormat(pdf, widths[3], 7, fmt.Sprintf("%d", t.pieces), "1", 0, "C", false, 0, "")
+		fonts.CellFormat(pdf, widths[4], 7, fmt.Sprintf("%.3f", t.stockArea/1e6), "1", 0, "R", false, 0, "")
+		fonts.CellFormat(pdf, widths[5], 7, fmt.Sprintf("%.1f", waste), "1", 1, "R", false, 0, "")
+	}
+
+	if len(plan.Unplaced) > 0 {
+		pdf.Ln(6)
+		fonts.SetFont(pdf, "B", 12)
+		fonts.CellFormat(pdf, 0, 8, "Not nested", "", 1, "L", false, 0, "")
+		fonts.SetFont(pdf, "", 10)
+		for _, u := range plan.Unplaced {
+			line := fmt.Sprintf("%d x %s %s - %s", u.Pieces, u.Thickness, u.Dimension, u.Reason)
+			fonts.CellFormat(pdf, 0, 6, tr(line), "", 1, "L", false, 0, "")
+		}
+	}
+
+	// One layout page per sheet
+	for i, sheet := range plan.Sheets {
+		pdf.AddPage()
+
+		usedLength := sheet.UsedLengthMM()
+		title := fmt.Sprintf("Sheet %d of %d - %s on %s, %d pieces, waste %.1f%%",
+			i+1, len(plan.Sheets), sheet.Thickness, sheet.Stock.Label, len(sheet.Placements), sheet.WastePercent())
+		if sheet.Stock.Roll {
+			title += fmt.Sprintf(", cut roll at %.0f mm", usedLength)
+		}
+		fonts.SetFont(pdf, "B", 12)
+		fonts.CellFormat(pdf, 0, 8, tr(title), "", 1, "L", false, 0, "")
+
+		// Draw with the stock length across the page
+		areaX, areaY := 10.0, 25.0
+		areaW, areaH := 277.0, 170.0
+		drawLength := sheet.Stock.LengthMM
+		if sheet.Stock.Roll {
+			drawLength = usedLength
+		}
+		scale := math.Min(areaW/drawLength, areaH/sheet.Stock.WidthMM)
+
+		pdf.SetDrawColor(0, 0, 0)
+		pdf.SetLineWidth(0.4)
+		pdf.SetFillColor(242, 242, 242)
+		pdf.Rect(areaX, areaY, drawLength*scale, sheet.Stock.WidthMM*scale, "FD")
+
+		pdf.SetLineWidth(0.2)
+		pdf.SetFillColor(198, 224, 180)
+		for _, p := range sheet.Placements {
+			x := areaX + p.Y*scale
+			y := areaY + p.X*scale
+			w := p.H * scale
+			h := p.W * scale
+			pdf.Rect(x, y, w, h, "FD")
+
+			label := p.Label
+			if p.Rotated {
+				label += " (R)"
+			}
+			fontSize := math.Min(9, h*2)
+			if fontSize < 4 {
+				continue
+			}
+			fonts.SetFont(pdf, "", fontSize)
+			if fonts.StringWidth(pdf, tr(label)) > w-1 {
+				continue
+			}
+			pdf.SetXY(x, y+h/2-fontSize*0.2)
+			fonts.CellFormat(pdf, w, fontSize*0.4, tr(label), "", 0, "C", false, 0, "")
+		}
+	}
+
+	return pdf.OutputFileAndClose(filename)
+}
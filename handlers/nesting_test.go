@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func nestGroup(thickness, dimension string, pieces int) cutListGroup {
+	parsedThickness, _ := parseThickness(thickness)
+	parsedDimension, _ := parseDimension(dimension)
+	return cutListGroup{
+		Thickness: thickness, Dimension: dimension, Pieces: pieces,
+		ParsedThickness: parsedThickness, ParsedDimension: parsedDimension,
+	}
+}
+
+var testStock = stockSizes{
+	3: {Label: "1000x2000 mm", WidthMM: 1000, LengthMM: 2000},
+	5: {Label: "1000x2000 mm", WidthMM: 1000, LengthMM: 2000},
+}
+
+func TestNestingFitsOneSheet(t *testing.T) {
+	plan := planNesting([]cutListGroup{nestGroup("5mm", "500x1000 mm", 4)}, testStock)
+
+	if len(plan.Sheets) != 1 || len(plan.Unplaced) != 0 {
+		t.Fatalf("got %d sheets and %+v unplaced, want 1 sheet", len(plan.Sheets), plan.Unplaced)
+	}
+	if n := len(plan.Sheets[0].Placements); n != 4 {
+		t.Errorf("placed %d pieces, want 4", n)
+	}
+	if waste := plan.Sheets[0].WastePercent(); waste > 0.001 {
+		t.Errorf("waste = %.2f%%, want 0", waste)
+	}
+}
+
+func TestNestingRotatesPieces(t *testing.T) {
+	// Wider than the sheet, so only fits turned
+	plan := planNesting([]cutListGroup{nestGroup("5mm", "1200x500 mm", 2)}, testStock)
+
+	if len(plan.Sheets) != 1 || len(plan.Unplaced) != 0 {
+		t.Fatalf("got %d sheets and %+v unplaced, want 1 sheet", len(plan.Sheets), plan.Unplaced)
+	}
+	for _, p := range plan.Sheets[0].Placements {
+		if !p.Rotated || p.W != 500 || p.H != 1200 {
+			t.Errorf("placement %+v, want rotated to 500x1200", p)
+		}
+	}
+}
+
+func TestNestingRejectsOversizePieces(t *testing.T) {
+	plan := planNesting([]cutListGroup{
+		nestGroup("5mm", "2500x1200 mm", 1),
+		nestGroup("5mm", "500x500 mm", 1),
+	}, testStock)
+
+	if len(plan.Unplaced) != 1 || !strings.HasPrefix(plan.Unplaced[0].Reason, "larger than stock") {
+		t.Fatalf("unplaced = %+v, want the oversize piece", plan.Unplaced)
+	}
+	if len(plan.Sheets) != 1 || len(plan.Sheets[0].Placements) != 1 {
+		t.Errorf("got %d sheets, want the small piece on one sheet", len(plan.Sheets))
+	}
+}
+
+func TestNestingSeparatesThicknesses(t *testing.T) {
+	plan := planNesting([]cutListGroup{
+		nestGroup("5mm", "500x1000 mm", 1),
+		nestGroup("0.5cm", "500x1000 mm", 1), // same stock as 5mm
+		nestGroup("3mm", "500x1000 mm", 1),
+		nestGroup("8mm", "500x1000 mm", 1), // no stock
+	}, testStock)
+
+	if len(plan.Sheets) != 2 {
+		t.Fatalf("got %d sheets, want one per stocked thickness", len(plan.Sheets))
+	}
+	if s := plan.Sheets[0]; s.ThickMM != 3 || len(s.Placements) != 1 {
+		t.Errorf("first sheet %.1fmm with %d pieces, want 3mm with 1", s.ThickMM, len(s.Placements))
+	}
+	if s := plan.Sheets[1]; s.ThickMM != 5 || len(s.Placements) != 2 {
+		t.Errorf("second sheet %.1fmm with %d pieces, want 5mm with 2", s.ThickMM, len(s.Placements))
+	}
+	if len(plan.Unplaced) != 1 || plan.Unplaced[0].Reason != "no stock size for thickness" {
+		t.Errorf("unplaced = %+v, want the 8mm piece", plan.Unplaced)
+	}
+}
@@ -0,0 +1,141 @@
+// handlers/ocr.go
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ocrResult is the recognised text of a single page
+type ocrResult struct {
+	Text       string
+	Confidence float64 // mean word confidence, 0-100
+}
+
+// ocrEngine recognises text on a single PDF page. It is only consulted for
+// pages that have no text layer.
+type ocrEngine interface {
+	RecognizePage(pdfPath, password string, pageNum int) (ocrResult, error)
+}
+
+// pdfOCREngine is the engine used by extractPDFPages. It is nil when no OCR
+// backend is installed, in which case image-only pages stay empty.
+var pdfOCREngine ocrEngine = newTesseractEngine()
+
+// tesseractEngine rasterises a page with pdftoppm and runs local Tesseract
+type tesseractEngine struct {
+	Language string
+	DPI      int
+}
+
+func newTesseractEngine() ocrEngine {
+	if _, err := exec.LookPath("tesseract"); err != nil {
+		return nil
+	}
+	if _, err := exec.LookPath("pdftoppm"); err != nil {
+		return nil
+	}
+	return tesseractEngine{Language: "eng", DPI: 300}
+}
+
+func (t tesseractEngine) RecognizePage(pdfPath, password string, pageNum int) (ocrResult, error) {
+	tempDir, err := os.MkdirTemp("", "ocr_page_")
+	if err != nil {
+		return ocrResult{}, fmt.Errorf("failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Rasterise just this page
+	imageBase := filepath.Join(tempDir, "page")
+	page := strconv.Itoa(pageNum)
+	args := append(popplerPasswordArgs(password),
+		"-r", strconv.Itoa(t.DPI), "-f", page, "-l", page, "-png", "-singlefile", pdfPath, imageBase)
+	if err := exec.Command("pdftoppm", args...).Run(); err != nil {
+		return ocrResult{}, fmt.Errorf("pdftoppm failed on page %d: %v", pageNum, err)
+	}
+
+	// Recognise as TSV so we get per-word confidences
+	outBase := filepath.Join(tempDir, "ocr")
+	cmd := exec.Command("tesseract", imageBase+".png", outBase, "-l", t.Language, "tsv")
+	if err := cmd.Run(); err != nil {
+		return ocrResult{}, fmt.Errorf("tesseract failed on page %d: %v", pageNum, err)
+	}
+
+	tsv, err := os.ReadFile(outBase + ".tsv")
+	if err != nil {
+		return ocrResult{}, fmt.Errorf("failed to read OCR output: %v", err)
+	}
+
+	return parseTesseractTSV(tsv), nil
+}
+
+// parseTesseractTSV rebuilds line-oriented text from Tesseract's TSV output
+// and averages the confidence of the recognised words
+func parseTesseractTSV(data []byte) ocrResult {
+	var lines []string
+	var current []string
+	lastLine := ""
+	confSum := 0.0
+	confCount := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+
+		// level page block par line word left top width height conf text
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 12 || fields[0] != "5" {
+			continue
+		}
+
+		word := strings.TrimSpace(fields[11])
+		if word == "" {
+			continue
+		}
+
+		lineKey := strings.Join(fields[2:5], ".")
+		if lineKey != lastLine && len(current) > 0 {
+			lines = append(lines, strings.Join(current, " "))
+			current = nil
+		}
+		lastLine = lineKey
+		current = append(current, word)
+
+		if conf, err := strconv.ParseFloat(fields[10], 64); err == nil && conf >= 0 {
+			confSum += conf
+			confCount++
+		}
+	}
+	if len(current) > 0 {
+		lines = append(lines, strings.Join(current, " "))
+	}
+
+	result := ocrResult{Text: strings.Join(lines, "\n")}
+	if confCount > 0 {
+		result.Confidence = confSum / float64(confCount)
+	}
+	return result
+}
+
+// stubOCREngine returns canned results per page number. It lets tests
+// exercise the OCR path without Tesseract installed.
+type stubOCREngine struct {
+	Pages map[int]ocrResult
+}
+
+func (s stubOCREngine) RecognizePage(pdfPath, password string, pageNum int) (ocrResult, error) {
+	if result, ok := s.Pages[pageNum]; ok {
+		return result, nil
+	}
+	return ocrResult{}, nil
+}
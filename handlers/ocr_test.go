@@ -0,0 +1,97 @@
+package handlers
+
+import "testing"
+
+func testCatalog(skus ...string) *pdfCatalog {
+	skuMap := make(map[string]PDFSKUMapping)
+	for _, sku := range skus {
+		skuMap[sku] = PDFSKUMapping{SKU: sku, Thickness: "5mm", Dimension: "24x36 in"}
+	}
+	return newPDFCatalog(skuMap, nil, nil)
+}
+
+func TestSplitPDFPagesRecognisesOnlyEmptyPages(t *testing.T) {
+	ocr := stubOCREngine{Pages: map[int]ocrResult{
+		1: {Text: "should not be used", Confidence: 90},
+		2: {Text: "Order Number: 111-1111111-1111111\nMRC-MR-0530", Confidence: 82.5},
+	}}
+
+	// pdftotext ends every page with a form feed
+	pages := splitPDFPages("Tax Invoice\f  \n\f", "in.pdf", "", ocr)
+
+	if len(pages) != 3 {
+		t.Fatalf("got %d pages, want 3", len(pages))
+	}
+	if pages[0].OCR || pages[0].Text != "Tax Invoice" {
+		t.Errorf("page 1 = %+v, want its text layer", pages[0])
+	}
+	if !pages[1].OCR || pages[1].OCRConfidence != 82.5 || pages[1].Text != ocr.Pages[2].Text {
+		t.Errorf("page 2 = %+v, want the OCR result", pages[1])
+	}
+	if pages[2].OCR {
+		t.Errorf("element after the final form feed was sent to OCR")
+	}
+}
+
+func TestSplitPDFPagesWithoutOCREngine(t *testing.T) {
+	pages := splitPDFPages("Tax Invoice\f\f", "in.pdf", "", nil)
+	if pages[1].OCR || pages[1].Text != "" {
+		t.Errorf("page 2 = %+v, want it left empty", pages[1])
+	}
+}
+
+func TestOCRLinesAreFlagged(t *testing.T) {
+	ocr := stubOCREngine{Pages: map[int]ocrResult{
+		1: {Text: "Order Number: 111-1111111-1111111\nMRC-MR-0530", Confidence: 91},
+		2: {Text: "Order Number: 222-2222222-2222222\nMRC-MR-0531", Confidence: 41},
+	}}
+	pages := splitPDFPages("\f\f", "in.pdf", "", ocr)
+
+	orders, _, err := processPDFPages(pages, testCatalog("MRC-MR-0530", "MRC-MR-0531"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("got %d lines, want 2", len(orders))
+	}
+
+	want := []struct {
+		page   int
+		source string
+		conf   string
+	}{
+		{1, "OCR", "91.0"},
+		{2, "OCR (low confidence)", "41.0"},
+	}
+	for i, w := range want {
+		o := orders[i]
+		if o.PageNumber != w.page || o.textSource() != w.source || o.ocrConfidenceString() != w.conf {
+			t.Errorf("line %d: page %d, source %q, confidence %q; want %d, %q, %q",
+				i+1, o.PageNumber, o.textSource(), o.ocrConfidenceString(), w.page, w.source, w.conf)
+		}
+	}
+}
+
+func TestTextFallbackKeepsPageAndOCR(t *testing.T) {
+	// The SKU comes before any order number, so no page pairs on its own
+	// and processTextSimple pairs across pages
+	pages := []pdfPage{
+		{Number: 1, Text: "MRC-MR-0530", OCR: true, OCRConfidence: 55},
+		{Number: 2, Text: "Tax Invoice\nOrder Number: 111-1111111-1111111"},
+	}
+
+	orders, diag, err := processPDFPages(pages, testCatalog("MRC-MR-0530"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diag.Fallbacks) != 1 || diag.Fallbacks[0] != fallbackProcessTextSimple {
+		t.Errorf("fallbacks = %v, want processTextSimple", diag.Fallbacks)
+	}
+	if len(orders) != 1 {
+		t.Fatalf("got %d lines, want 1", len(orders))
+	}
+	o := orders[0]
+	if o.PageNumber != 1 || !o.OCR || o.OCRConfidence != 55 {
+		t.Errorf("line = page %d, OCR %v, confidence %v; want page 1 from OCR at 55", o.PageNumber, o.OCR, o.OCRConfidence)
+	}
+}
@@ -0,0 +1,126 @@
+// handlers/order_preview.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PreviewLine is an extracted line as shown for review. The same rows come
+// back in the confirmedLines form field, with the SKU and quantity edited.
+type PreviewLine struct {
+	OrderNumber   string  `json:"order_number"`
+	OrderDate     string  `json:"order_date,omitempty"`
+	Page          int     `json:"page"`
+	LabelPage     int     `json:"label_page,omitempty"`
+	OCR           bool    `json:"ocr,omitempty"`
+	OCRConfidence float64 `json:"ocr_confidence,omitempty"`
+	SKU           string  `json:"sku"`
+	KitSKU        string  `json:"kit_sku,omitempty"`
+	Quantity      int     `json:"quantity"` // 0 drops the line
+
+	// How the SKU resolved, for the reviewer; ignored when confirming
+	CatalogSKU  string   `json:"catalog_sku,omitempty"`
+	MatchType   string   `json:"match_type,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+	Thickness   string   `json:"thickness,omitempty"`
+	Dimension   string   `json:"dimension,omitempty"`
+}
+
+// OrderPreview is the extracted lines and the catalog SKUs they can be
+// corrected to
+type OrderPreview struct {
+	Lines       []PreviewLine `json:"lines"`
+	CatalogSKUs []string      `json:"catalog_skus"`
+}
+
+// newOrderPreview lists orders for review. Unmatched SKUs carry the
+// catalog's closest SKUs as suggestions.
+func newOrderPreview(orders []PDFOrderData, catalog *pdfCatalog) *OrderPreview {
+	preview := &OrderPreview{Lines: make([]PreviewLine, 0, len(orders))}
+
+	for _, order := range orders {
+		line := PreviewLine{
+			OrderNumber:   order.OrderNumber,
+			OrderDate:     order.OrderDate,
+			Page:          order.PageNumber,
+			LabelPage:     order.LabelPage,
+			OCR:           order.OCR,
+			OCRConfidence: order.OCRConfidence,
+			SKU:           order.SKUID,
+			KitSKU:        order.KitSKU,
+			Quantity:      order.Quantity,
+			CatalogSKU:    order.CatalogSKU,
+			MatchType:     order.MatchType,
+			Thickness:     order.Thickness,
+			Dimension:     order.Dimension,
+		}
+		if !matchFound(order.MatchType) {
+			for _, s := range catalog.matcher.Match(order.SKUID).Suggestions {
+				line.Suggestions = append(line.Suggestions, s.SKU)
+			}
+		}
+		preview.Lines = append(preview.Lines, line)
+	}
+
+	for sku := range catalog.SKUs {
+		preview.CatalogSKUs = append(preview.CatalogSKUs, sku)
+	}
+	for kit := range catalog.Kits {
+		if _, exists := catalog.SKUs[kit]; !exists {
+			preview.CatalogSKUs = append(preview.CatalogSKUs, kit)
+		}
+	}
+	sort.Strings(preview.CatalogSKUs)
+
+	return preview
+}
+
+// confirmedOrders rebuilds the order lines from the reviewed rows, resolving
+// each SKU against the catalog again. Kit components stay as confirmed; a
+// kit SKU typed in for a line is expanded.
+func confirmedOrders(data string, catalog *pdfCatalog) ([]PDFOrderData, error) {
+	var lines []PreviewLine
+	if err := json.Unmarshal([]byte(data), &lines); err != nil {
+		return nil, fmt.Errorf("failed to read confirmed lines: %v", err)
+	}
+
+	var orders []PDFOrderData
+	for i, line := range lines {
+		if line.Quantity < 1 {
+			continue // removed in review
+		}
+		sku := strings.TrimSpace(line.SKU)
+		if line.OrderNumber == "" || sku == "" {
+			return nil, fmt.Errorf("confirmed line %d needs an order number and SKU", i+1)
+		}
+
+		order := PDFOrderData{
+			OrderNumber:   line.OrderNumber,
+			OrderDate:     line.OrderDate,
+			SKUID:         sku,
+			PageNumber:    line.Page,
+			LabelPage:     line.LabelPage,
+			OCR:           line.OCR,
+			OCRConfidence: line.OCRConfidence,
+			Quantity:      line.Quantity,
+		}
+		catalog.resolve(&order)
+
+		if line.KitSKU != "" {
+			if _, isKit := catalog.Kits[order.CatalogSKU]; !isKit {
+				order.KitSKU = line.KitSKU
+				orders = append(orders, order)
+				continue
+			}
+		}
+		orders = append(orders, catalog.expandKit(order)...)
+	}
+
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("no confirmed lines")
+	}
+	return orders, nil
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConfirmedOrdersUseEditedLines(t *testing.T) {
+	catalog := testCatalog("MRC-MR-0530", "MRC-MR-0531")
+	data := `[
+		{"order_number": "111-1111111-1111111", "page": 1, "sku": "MRC-MR-0530", "quantity": 2, "match_type": "exact"},
+		{"order_number": "111-1111111-1111111", "page": 1, "sku": " MRC-MR-0531 ", "quantity": 1, "match_type": "none", "catalog_sku": "MRC-MR-0530"},
+		{"order_number": "222-2222222-2222222", "page": 2, "sku": "MRC-MR-0530", "quantity": 0},
+		{"order_number": "333-3333333-3333333", "page": 3, "label_page": 4, "sku": "MRC-XX-9999", "quantity": 1}
+	]`
+
+	orders, err := confirmedOrders(data, catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 3 {
+		t.Fatalf("got %d lines, want 3 with the zero quantity dropped: %+v", len(orders), orders)
+	}
+
+	if o := orders[0]; o.CatalogSKU != "MRC-MR-0530" || o.Quantity != 2 {
+		t.Errorf("line 1 = %s x%d, want MRC-MR-0530 x2", o.CatalogSKU, o.Quantity)
+	}
+	// The edited SKU is resolved again; the stale match sent back is ignored
+	if o := orders[1]; o.SKUID != "MRC-MR-0531" || o.CatalogSKU != "MRC-MR-0531" || o.MatchType != matchExact || o.Dimension != "24x36 in" {
+		t.Errorf("edited line = %+v, want MRC-MR-0531 resolved from the catalog", o)
+	}
+	// Unknown SKUs are kept, unmatched, so they show up in the output
+	if o := orders[2]; o.SKUID != "MRC-XX-9999" || o.CatalogSKU != "" || o.MatchType != matchNone || o.Thickness != "N/A" || o.LabelPage != 4 {
+		t.Errorf("unknown line = %+v, want it kept as unmatched", o)
+	}
+}
+
+func TestConfirmedOrdersRejectIncompleteLines(t *testing.T) {
+	catalog := testCatalog("MRC-MR-0530")
+
+	for name, data := range map[string]string{
+		"empty SKU":       `[{"order_number": "111-1111111-1111111", "sku": " ", "quantity": 1}]`,
+		"no order number": `[{"sku": "MRC-MR-0530", "quantity": 1}]`,
+		"all removed":     `[{"order_number": "111-1111111-1111111", "sku": "MRC-MR-0530", "quantity": 0}]`,
+		"not JSON":        `lines`,
+	} {
+		if _, err := confirmedOrders(data, catalog); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestOrderPreviewJSONKeys(t *testing.T) {
+	preview := newOrderPreview([]PDFOrderData{{OrderNumber: "111-1111111-1111111", SKUID: "MRC-MR-0530", PageNumber: 1, Quantity: 1}},
+		testCatalog("MRC-MR-0530"))
+
+	data, err := json.Marshal(preview)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"order_number"`, `"catalog_skus"`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("preview JSON %s has no %s key", data, key)
+		}
+	}
+}
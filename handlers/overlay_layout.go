@@ -0,0 +1,127 @@
+// handlers/overlay_layout.go
+package handlers
+
+import (
+	"math"
+	"os/exec"
+	"regexp"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const mmPerPt = 25.4 / 72
+
+// pageGeometry is the media box and rotation of a source PDF page
+type pageGeometry struct {
+	WidthPt  float64
+	HeightPt float64
+	Rotation int // clockwise degrees: 0, 90, 180 or 270
+}
+
+// a4Geometry is assumed for pages pdfinfo cannot describe
+var a4Geometry = pageGeometry{WidthPt: 595.28, HeightPt: 841.89}
+
+// DisplaySizeMM is the page size as viewed, i.e. after rotation. Overlay
+// pages are built at this size and pdftk rotates them onto the page.
+func (g pageGeometry) DisplaySizeMM() (float64, float64) {
+	w, h := g.WidthPt*mmPerPt, g.HeightPt*mmPerPt
+	if g.Rotation == 90 || g.Rotation == 270 {
+		return h, w
+	}
+	return w, h
+}
+
+var (
+	pdfinfoMediaBoxRegex = regexp.MustCompile(`(?m)^Page\s+(\d+)\s+MediaBox:\s+(-?[\d.]+)\s+(-?[\d.]+)\s+(-?[\d.]+)\s+(-?[\d.]+)`)
+	pdfinfoRotationRegex = regexp.MustCompile(`(?m)^Page\s+(\d+)\s+rot:\s+(-?\d+)`)
+)
+
+// getPDFPageGeometry reads every page's media box and rotation with pdfinfo.
+// Pages it cannot describe are missing from the map.
+func getPDFPageGeometry(pdfPath, password string, totalPages int) map[int]pageGeometry {
+	geometry := make(map[int]pageGeometry)
+
+	args := append(popplerPasswordArgs(password), "-f", "1", "-l", strconv.Itoa(totalPages), "-box", pdfPath)
+	output, err := exec.Command("pdfinfo", args...).Output()
+	if err != nil {
+		return geometry
+	}
+
+	for _, m := range pdfinfoMediaBoxRegex.FindAllStringSubmatch(string(output), -1) {
+		page, _ := strconv.Atoi(m[1])
+		x0, _ := strconv.ParseFloat(m[2], 64)
+		y0, _ := strconv.ParseFloat(m[3], 64)
+		x1, _ := strconv.ParseFloat(m[4], 64)
+		y1, _ := strconv.ParseFloat(m[5], 64)
+		if x1-x0 > 0 && y1-y0 > 0 {
+			geometry[page] = pageGeometry{WidthPt: x1 - x0, HeightPt: y1 - y0}
+		}
+	}
+
+	for _, m := range pdfinfoRotationRegex.FindAllStringSubmatch(string(output), -1) {
+		page, _ := strconv.Atoi(m[1])
+		rotation, _ := strconv.Atoi(m[2])
+		if g, found := geometry[page]; found {
+			g.Rotation = ((rotation % 360) + 360) % 360
+			geometry[page] = g
+		}
+	}
+
+	return geometry
+}
+
+// pageGeometryFor returns the geometry of page, or A4 when it is unknown
+func pageGeometryFor(geometry map[int]pageGeometry, page int) pageGeometry {
+	if g, found := geometry[page]; found {
+		return g
+	}
+	return a4Geometry
+}
+
+// overlayLayout positions annotations relative to the page, so the rules
+// that were written for A4 also fit Letter, A5 and 4x6 labels. On A4 it
+// gives the original positions: a 10 mm margin, a 35 mm band starting at
+// 245 mm (or 12 mm from the top) and 120 mm wide columns.
+type overlayLayout struct {
+	Width       float64 // mm
+	Height      float64
+	Margin      float64
+	BandTop     float64
+	BandHeight  float64
+	ColumnWidth float64
+	Scale       float64 // text size relative to A4
+}
+
+func newOverlayLayout(g pageGeometry, anchor string) overlayLayout {
+	w, h := g.DisplaySizeMM()
+	layout := overlayLayout{
+		Width:       w,
+		Height:      h,
+		Margin:      math.Min(w, h) * 10 / 210,
+		BandTop:     h * 245 / 297,
+		BandHeight:  h * 35 / 297,
+		ColumnWidth: w * 120 / 210,
+		Scale:       math.Min(1, math.Min(w/210, h/297)),
+	}
+	if anchor == anchorTopLeft || anchor == anchorTopRight {
+		layout.BandTop = h * 12 / 297
+	}
+	return layout
+}
+
+// Right is the x coordinate right-aligned content ends at
+func (l overlayLayout) Right() float64 {
+	return l.Width - l.Margin
+}
+
+// FontSize scales size for the page, keeping it legible on small labels
+func (l overlayLayout) FontSize(size float64) float64 {
+	return math.Max(6, size*l.Scale)
+}
+
+// addOverlayPage starts a page of the given geometry's displayed size
+func addOverlayPage(pdf *gofpdf.Fpdf, g pageGeometry) {
+	w, h := g.DisplaySizeMM()
+	pdf.AddPageFormat("P", gofpdf.SizeType{Wd: w, Ht: h})
+}
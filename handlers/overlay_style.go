@@ -0,0 +1,286 @@
+// handlers/overlay_style.go
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const overlayPresetStorePath = "./data/overlay_presets.json"
+
+// Where the annotation block sits on the page
+const (
+	anchorBottomLeft  = "bottom-left"
+	anchorBottomRight = "bottom-right"
+	anchorTopLeft     = "top-left"
+	anchorTopRight    = "top-right"
+)
+
+// Default annotation text for stamped invoice pages, and for the standalone
+// annotation pages written when pdftk is missing
+const (
+	defaultOverlayTemplate       = `{{if gt .Quantity 1}}{{.Quantity}} x {{end}}Thickness: {{.Thickness}} | Dimension: {{.Dimension}}{{if .KitSKU}} | Kit: {{.KitSKU}}{{end}}`
+	defaultSimpleOverlayTemplate = `Order: {{.OrderNumber}} | SKU: {{.SKUID}} | Thickness: {{.Thickness}} | Dimension: {{.Dimension}}{{if gt .Quantity 1}} | Qty: {{.Quantity}}{{end}}{{if .KitSKU}} | Kit: {{.KitSKU}}{{end}}`
+)
+
+// Fonts available to overlays, by lower-case name. Apart from the default
+// these are core PDF fonts, which only cover Latin-1.
+var overlayFonts = map[string]string{
+	"default":   "", // the embedded UTF-8 font
+	"arial":     "Arial",
+	"helvetica": "Helvetica",
+	"times":     "Times",
+	"courier":   "Courier",
+}
+
+// OverlayStyle is the content and look of overlay annotations. An empty
+// Template uses the built-in text and an empty FontFamily the embedded
+// UTF-8 font.
+type OverlayStyle struct {
+	Name              string    `json:"name,omitempty"`
+	Template          string    `json:"template,omitempty"`
+	FontFamily        string    `json:"font_family"`
+	FontSize          float64   `json:"font_size"`
+	TextColor         string    `json:"text_color"` // #RRGGBB
+	BackgroundOpacity float64   `json:"background_opacity"`
+	Anchor            string    `json:"anchor"`
+	UpdatedAt         time.Time `json:"updated_at,omitempty"`
+}
+
+func defaultOverlayStyle() OverlayStyle {
+	return OverlayStyle{
+		FontSize:          14,
+		TextColor:         "#000000",
+		BackgroundOpacity: 0.9,
+		Anchor:            anchorBottomLeft,
+	}
+}
+
+// overlayText is an OverlayStyle ready to draw
+type overlayText struct {
+	Style   OverlayStyle
+	tmpl    *template.Template // nil for the built-in text
+	r, g, b int
+}
+
+var (
+	defaultOverlayTmpl       = template.Must(newOverlayTemplate().Parse(defaultOverlayTemplate))
+	defaultSimpleOverlayTmpl = template.Must(newOverlayTemplate().Parse(defaultSimpleOverlayTemplate))
+)
+
+func newOverlayTemplate() *template.Template {
+	return template.New("overlay").Funcs(template.FuncMap{
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	})
+}
+
+// compile parses the style's template and colour. The template is tried
+// against an empty order so references to unknown fields fail here rather
+// than halfway through a PDF.
+func (s OverlayStyle) compile() (overlayText, error) {
+	text := overlayText{Style: s}
+
+	if strings.TrimSpace(s.Template) != "" {
+		tmpl, err := newOverlayTemplate().Parse(s.Template)
+		if err != nil {
+			return text, fmt.Errorf("invalid overlay template: %v", err)
+		}
+		if err := tmpl.Execute(&bytes.Buffer{}, PDFOrderData{}); err != nil {
+			return text, fmt.Errorf("invalid overlay template: %v", err)
+		}
+		text.tmpl = tmpl
+	}
+
+	var err error
+	text.r, text.g, text.b, err = parseHexColor(s.TextColor)
+	if err != nil {
+		return text, err
+	}
+	return text, nil
+}
+
+// render is the annotation for one order line, on a single line
+func (t overlayText) render(order PDFOrderData, simple bool) (string, error) {
+	tmpl := t.tmpl
+	if tmpl == nil {
+		tmpl = defaultOverlayTmpl
+		if simple {
+			tmpl = defaultSimpleOverlayTmpl
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, order); err != nil {
+		return "", fmt.Errorf("failed to render overlay text for order %s: %v", order.OrderNumber, err)
+	}
+	return strings.Join(strings.Fields(buf.String()), " "), nil
+}
+
+func parseHexColor(value string) (r, g, b int, err error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if hex == "" {
+		return 0, 0, 0, nil
+	}
+	n, parseErr := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || parseErr != nil {
+		return 0, 0, 0, fmt.Errorf("invalid overlay colour %q: use #RRGGBB", value)
+	}
+	return int(n >> 16), int(n >> 8 & 0xff), int(n & 0xff), nil
+}
+
+// applyOverlayForm overrides style with the overlay form fields that are set
+func applyOverlayForm(style OverlayStyle, r *http.Request) (OverlayStyle, error) {
+	if value := r.FormValue("overlayTemplate"); strings.TrimSpace(value) != "" {
+		style.Template = value
+	}
+
+	if value := strings.TrimSpace(r.FormValue("overlayFont")); value != "" {
+		font, found := overlayFonts[strings.ToLower(value)]
+		if !found {
+			return style, fmt.Errorf("unknown overlay font %q: use Default, Arial, Helvetica, Times or Courier", value)
+		}
+		style.FontFamily = font
+	}
+
+	if value := strings.TrimSpace(r.FormValue("overlayFontSize")); value != "" {
+		size, err := strconv.ParseFloat(value, 64)
+		if err != nil || size < 6 || size > 36 {
+			return style, fmt.Errorf("overlay font size must be between 6 and 36")
+		}
+		style.FontSize = size
+	}
+
+	if value := strings.TrimSpace(r.FormValue("overlayColor")); value != "" {
+		if _, _, _, err := parseHexColor(value); err != nil {
+			return style, err
+		}
+		style.TextColor = "#" + strings.ToUpper(strings.TrimPrefix(value, "#"))
+	}
+
+	if value := strings.TrimSpace(r.FormValue("overlayOpacity")); value != "" {
+		opacity, err := strconv.ParseFloat(value, 64)
+		if err != nil || opacity < 0 || opacity > 1 {
+			return style, fmt.Errorf("overlay background opacity must be between 0 and 1")
+		}
+		style.BackgroundOpacity = opacity
+	}
+
+	if value := strings.TrimSpace(r.FormValue("overlayAnchor")); value != "" {
+		switch value {
+		case anchorBottomLeft, anchorBottomRight, anchorTopLeft, anchorTopRight:
+			style.Anchor = value
+		default:
+			return style, fmt.Errorf("unknown overlay position %q", value)
+		}
+	}
+
+	return style, nil
+}
+
+// requestOverlayText builds the overlay style for a request: the defaults,
+// replaced by the named preset if any, then overridden field by field
+func requestOverlayText(r *http.Request) (overlayText, error) {
+	style := defaultOverlayStyle()
+
+	if name := strings.TrimSpace(r.FormValue("overlayPreset")); name != "" {
+		preset, found, err := overlayPresets.Get(name)
+		if err != nil {
+			return overlayText{}, err
+		}
+		if !found {
+			return overlayText{}, fmt.Errorf("overlay preset not found: %s", name)
+		}
+		style = preset
+	}
+
+	style, err := applyOverlayForm(style, r)
+	if err != nil {
+		return overlayText{}, err
+	}
+	return style.compile()
+}
+
+type OverlayPresetListResult struct {
+	Success bool           `json:"success"`
+	Presets []OverlayStyle `json:"presets"`
+}
+
+// presetStore is the saved overlay styles, persisted as JSON
+type presetStore struct {
+	*jsonStore[OverlayStyle]
+}
+
+var overlayPresets = &presetStore{newJSONStore(overlayPresetStorePath, "overlay presets", func(p OverlayStyle) string { return p.Name })}
+
+// Put adds or replaces a preset
+func (s *presetStore) Put(preset OverlayStyle) error {
+	preset.UpdatedAt = time.Now()
+	return s.jsonStore.Put(preset)
+}
+
+// OverlayPresetsHandler manages saved overlay styles.
+//
+//	GET    /overlay-presets                   list presets
+//	POST   /overlay-presets  name, overlay*   save the form's style as a preset
+//	DELETE /overlay-presets?name=...          remove a preset
+func OverlayPresetsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		presets, err := overlayPresets.List()
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResult(w, OverlayPresetListResult{Success: true, Presets: presets})
+
+	case "POST":
+		name := strings.TrimSpace(r.FormValue("name"))
+		if name == "" {
+			writeJSONError(w, "name is required", http.StatusBadRequest)
+			return
+		}
+
+		style, err := applyOverlayForm(defaultOverlayStyle(), r)
+		if err == nil {
+			_, err = style.compile()
+		}
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		style.Name = name
+		if err := overlayPresets.Put(style); err != nil {
+			writeJSONError(w, "Failed to save preset: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONSuccess(w, "Overlay preset "+name+" saved", "", "")
+
+	case "DELETE":
+		name := strings.TrimSpace(r.URL.Query().Get("name"))
+		if name == "" {
+			writeJSONError(w, "name is required", http.StatusBadRequest)
+			return
+		}
+
+		found, err := overlayPresets.Delete(name)
+		if err != nil {
+			writeJSONError(w, "Failed to delete preset: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			writeJSONError(w, "Overlay preset not found: "+name, http.StatusNotFound)
+			return
+		}
+		writeJSONSuccess(w, "Overlay preset "+name+" deleted", "", "")
+
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,456 @@
+// handlers/pack_verify.go
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const runStoreDir = "./data/runs"
+
+// Order verification statuses
+const (
+	verifyPending  = "Pending"
+	verifyPartial  = "Partial"
+	verifyVerified = "Verified"
+	verifyMismatch = "Mismatch"
+)
+
+// packRun is one /process-pdf run kept for pack-station verification
+type packRun struct {
+	ID        string                `json:"id"`
+	CreatedAt time.Time             `json:"created_at"`
+	Lines     []PDFOrderData        `json:"lines"`
+	Scans     []packScan            `json:"scans"`
+	Orders    map[string]*packOrder `json:"orders"`
+}
+
+// packOrder is the verification state of one order in a run
+type packOrder struct {
+	Status     string    `json:"status"`
+	Packed     []int     `json:"packed"` // pieces scanned per line, parallel to lineIndexes
+	Mismatches int       `json:"mismatches"`
+	UpdatedAt  time.Time `json:"updated_at,omitempty"`  // last scan
+	VerifiedAt time.Time `json:"verified_at,omitempty"` // when the last piece was packed
+}
+
+// packScan is one product scan at the pack station
+type packScan struct {
+	OrderNumber string    `json:"order_number"`
+	Scanned     string    `json:"scanned"`
+	SKU         string    `json:"sku"` // the SKU the scan resolved to
+	Pass        bool      `json:"pass"`
+	Message     string    `json:"message"`
+	At          time.Time `json:"at"`
+}
+
+// lineIndexes returns the positions of an order's lines in the run
+func (r *packRun) lineIndexes(orderNumber string) []int {
+	var indexes []int
+	for i, line := range r.Lines {
+		if line.OrderNumber == orderNumber {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// runStore keeps pack runs as one JSON file per run
+type runStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+var packRuns = &runStore{dir: runStoreDir}
+
+var runIDRegex = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
+
+// newRunID names a run by its start time plus random bytes, so uploads in
+// the same second get different runs
+func newRunID(timestamp string) string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return timestamp + "_" + hex.EncodeToString(b)
+}
+
+func (s *runStore) path(id string) (string, error) {
+	if !runIDRegex.MatchString(id) {
+		return "", fmt.Errorf("invalid run ID %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func (s *runStore) loadLocked(id string) (*packRun, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var run packRun
+	found, err := readJSONFile(path, &run, "run "+id)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("run %s not found", id)
+	}
+	return &run, nil
+}
+
+func (s *runStore) saveLocked(run *packRun) error {
+	path, err := s.path(run.ID)
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(path, run, "run "+run.ID)
+}
+
+// Create stores the extracted lines of a new run with every order pending
+func (s *runStore) Create(id string, lines []PDFOrderData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := &packRun{
+		ID:        id,
+		CreatedAt: time.Now(),
+		Lines:     lines,
+		Orders:    make(map[string]*packOrder),
+	}
+	for _, line := range lines {
+		if order, exists := run.Orders[line.OrderNumber]; exists {
+			order.Packed = append(order.Packed, 0)
+			continue
+		}
+		run.Orders[line.OrderNumber] = &packOrder{Status: verifyPending, Packed: []int{0}}
+	}
+
+	return s.saveLocked(run)
+}
+
+// Get returns a stored run
+func (s *runStore) Get(id string) (*packRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.loadLocked(id)
+}
+
+// Verify records a product scan against an order of a run
+func (s *runStore) Verify(id, orderNumber, scanned string, aliases map[string]string) (packScan, *packOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, err := s.loadLocked(id)
+	if err != nil {
+		return packScan{}, nil, err
+	}
+
+	order, exists := run.Orders[orderNumber]
+	if !exists {
+		return packScan{}, nil, fmt.Errorf("order %s is not in run %s", orderNumber, id)
+	}
+
+	scan := checkScan(run, order, orderNumber, scanned, aliases)
+	run.Scans = append(run.Scans, scan)
+	order.UpdatedAt = scan.At
+	if order.Status == verifyVerified && order.VerifiedAt.IsZero() {
+		order.VerifiedAt = scan.At
+	}
+
+	if err := s.saveLocked(run); err != nil {
+		return packScan{}, nil, err
+	}
+	return scan, order, nil
+}
+
+// checkScan matches a scanned value against the order's unpacked lines and
+// updates the order's status
+func checkScan(run *packRun, order *packOrder, orderNumber, scanned string, aliases map[string]string) packScan {
+	scan := packScan{OrderNumber: orderNumber, Scanned: scanned, At: time.Now()}
+	scan.SKU = scannedSKU(scanned, aliases)
+
+	// An overlay QR code identifies the order, never the product: its SKU
+	// field is printed from the invoice, so it would always match
+	if qrOrder := scannedOrder(scanned); qrOrder != "" {
+		if qrOrder != orderNumber {
+			order.Mismatches++
+			scan.Message = fmt.Sprintf("This label belongs to order %s, not %s", qrOrder, orderNumber)
+			order.Status = orderVerifyStatus(run, order, orderNumber, false)
+			return scan
+		}
+		scan.SKU = ""
+		scan.Message = fmt.Sprintf("That is the label of order %s; scan the product barcode", orderNumber)
+		return scan
+	}
+
+	indexes := run.lineIndexes(orderNumber)
+	matched := -1
+	alreadyPacked := false
+	for i, lineIndex := range indexes {
+		line := run.Lines[lineIndex]
+		if !scanMatchesLine(scan.SKU, line) {
+			continue
+		}
+		if order.Packed[i] >= max(line.Quantity, 1) {
+			alreadyPacked = true
+			continue
+		}
+		matched = i
+		break
+	}
+
+	switch {
+	case matched >= 0:
+		order.Packed[matched]++
+		scan.Pass = true
+		line := run.Lines[indexes[matched]]
+		scan.Message = fmt.Sprintf("%s %s %s (%d of %d)", scan.SKU, line.Thickness, line.Dimension,
+			order.Packed[matched], max(line.Quantity, 1))
+	case alreadyPacked:
+		order.Mismatches++
+		scan.Message = fmt.Sprintf("%s is already fully packed for this order", scan.SKU)
+	default:
+		order.Mismatches++
+		scan.Message = fmt.Sprintf("%s is not on order %s", scan.SKU, orderNumber)
+	}
+
+	order.Status = orderVerifyStatus(run, order, orderNumber, scan.Pass)
+	return scan
+}
+
+// orderVerifyStatus derives an order's status after a scan. A wrong scan
+// marks the order Mismatch until it is completely packed.
+func orderVerifyStatus(run *packRun, order *packOrder, orderNumber string, lastPass bool) string {
+	complete, started := true, false
+	for i, lineIndex := range run.lineIndexes(orderNumber) {
+		if order.Packed[i] > 0 {
+			started = true
+		}
+		if order.Packed[i] < max(run.Lines[lineIndex].Quantity, 1) {
+			complete = false
+		}
+	}
+
+	switch {
+	case complete:
+		return verifyVerified
+	case !lastPass || order.Status == verifyMismatch:
+		return verifyMismatch
+	case started:
+		return verifyPartial
+	}
+	return verifyPending
+}
+
+// scannedOrder returns the order number of an overlay QR code scan
+func scannedOrder(scanned string) string {
+	return qrPayloadField(scanned, "ORD:")
+}
+
+// qrPayloadField returns one field of an overlay QR code payload, or "" when
+// scanned is not a QR payload
+func qrPayloadField(scanned, prefix string) string {
+	scanned = strings.TrimSpace(scanned)
+	if !strings.HasPrefix(scanned, "ORD:") {
+		return ""
+	}
+	for _, field := range strings.Split(scanned, ";") {
+		if value, ok := strings.CutPrefix(field, prefix); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// scannedSKU extracts the SKU from a product scan: a stored marketplace
+// alias, or the value as scanned
+func scannedSKU(scanned string, aliases map[string]string) string {
+	scanned = strings.TrimSpace(scanned)
+	if sku, ok := aliases[scanned]; ok {
+		return sku
+	}
+	return scanned
+}
+
+// scanMatchesLine compares SKUs in normalised form so case, dashes and
+// zero-padding differences between labels and the catalog don't fail a scan
+func scanMatchesLine(sku string, line PDFOrderData) bool {
+	norm := normaliseSKU(sku)
+	for _, candidate := range []string{line.CatalogSKU, line.SKUID} {
+		if candidate != "" && normaliseSKU(candidate) == norm {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyResult is the JSON response to a pack-station scan or status query
+type VerifyResult struct {
+	Success bool                  `json:"success"`
+	Pass    bool                  `json:"pass,omitempty"`
+	Message string                `json:"message,omitempty"`
+	Status  string                `json:"status,omitempty"`
+	Order   string                `json:"order,omitempty"`
+	Lines   []VerifyLine          `json:"lines,omitempty"`
+	Orders  map[string]*packOrder `json:"orders,omitempty"`
+}
+
+// VerifyLine is one line of an order as shown at the pack station
+type VerifyLine struct {
+	SKU       string `json:"sku"`
+	Thickness string `json:"thickness"`
+	Dimension string `json:"dimension"`
+	Quantity  int    `json:"quantity"`
+	Packed    int    `json:"packed"`
+}
+
+func orderVerifyLines(run *packRun, orderNumber string) []VerifyLine {
+	order := run.Orders[orderNumber]
+	var lines []VerifyLine
+	for i, lineIndex := range run.lineIndexes(orderNumber) {
+		line := run.Lines[lineIndex]
+		sku := line.CatalogSKU
+		if sku == "" {
+			sku = line.SKUID
+		}
+		lines = append(lines, VerifyLine{
+			SKU:       sku,
+			Thickness: line.Thickness,
+			Dimension: line.Dimension,
+			Quantity:  max(line.Quantity, 1),
+			Packed:    order.Packed[i],
+		})
+	}
+	return lines
+}
+
+// VerifyHandler checks pack-station scans against a run's extracted orders.
+//
+//	GET  /verify?run=...               status of every order in the run
+//	GET  /verify?run=...&order=...     lines and status of one order
+//	POST /verify  run, order, scan     record a product scan for the order
+//
+// order may be an overlay QR code payload, which selects the order it names.
+func VerifyHandler(w http.ResponseWriter, r *http.Request) {
+	runID := strings.TrimSpace(r.FormValue("run"))
+	orderNumber := strings.TrimSpace(r.FormValue("order"))
+	if qrOrder := scannedOrder(orderNumber); qrOrder != "" {
+		orderNumber = qrOrder
+	}
+	if runID == "" {
+		writeJSONError(w, "run is required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		run, err := packRuns.Get(runID)
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		result := VerifyResult{Success: true}
+		if orderNumber == "" {
+			result.Orders = run.Orders
+		} else {
+			order, exists := run.Orders[orderNumber]
+			if !exists {
+				writeJSONError(w, fmt.Sprintf("Order %s is not in this run", orderNumber), http.StatusNotFound)
+				return
+			}
+			result.Status = order.Status
+			result.Order = orderNumber
+			result.Lines = orderVerifyLines(run, orderNumber)
+		}
+		writeJSONResult(w, result)
+
+	case "POST":
+		scanned := strings.TrimSpace(r.FormValue("scan"))
+		if orderNumber == "" || scanned == "" {
+			writeJSONError(w, "Both order and scan are required", http.StatusBadRequest)
+			return
+		}
+
+		aliases, err := skuAliases.Merged(nil)
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		scan, order, err := packRuns.Verify(runID, orderNumber, scanned, aliases)
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		run, err := packRuns.Get(runID)
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResult(w, VerifyResult{
+			Success: true,
+			Pass:    scan.Pass,
+			Message: scan.Message,
+			Status:  order.Status,
+			Lines:   orderVerifyLines(run, orderNumber),
+		})
+
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// VerifyExportHandler writes the run's lines in the PDF CSV layout with the
+// verification columns appended and returns a download link.
+//
+//	GET /verify/export?run=...
+func VerifyExportHandler(w http.ResponseWriter, r *http.Request) {
+	runID := strings.TrimSpace(r.FormValue("run"))
+	run, err := packRuns.Get(runID)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	fileName := run.ID + "_verification.csv"
+	if err := writeVerificationCSV(run, filepath.Join("./outputs", fileName)); err != nil {
+		writeJSONError(w, "Failed to export verification: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSONSuccess(w, "Verification exported", "/outputs/"+fileName, fileName)
+}
+
+// writeVerificationCSV writes the run's lines as writePDFToCSV does, plus
+// each line's order status, pieces packed, mismatched scans, when the order
+// was verified and its last scan
+func writeVerificationCSV(run *packRun, filename string) error {
+	packed := make([]int, len(run.Lines))
+	for orderNumber, order := range run.Orders {
+		for i, lineIndex := range run.lineIndexes(orderNumber) {
+			packed[lineIndex] = order.Packed[i]
+		}
+	}
+
+	extra := []string{"Verification Status", "Packed", "Mismatched Scans", "Verified At", "Last Scan"}
+	return writePDFCSVWithColumns(run.Lines, filename, extra, func(i int, line PDFOrderData) []string {
+		order := run.Orders[line.OrderNumber]
+		return []string{order.Status, fmt.Sprintf("%d", packed[i]), fmt.Sprintf("%d", order.Mismatches),
+			formatScanTime(order.VerifiedAt), formatScanTime(order.UpdatedAt)}
+	})
+}
+
+func formatScanTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
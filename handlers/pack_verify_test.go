@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOverlayQRDoesNotVerifyProduct(t *testing.T) {
+	store := &runStore{dir: t.TempDir()}
+	lines := []PDFOrderData{{OrderNumber: "111-1111111-1111111", SKUID: "MRC-MR-0530", CatalogSKU: "MRC-MR-0530", Quantity: 1}}
+	id := newRunID("1700000000")
+	if err := store.Create(id, lines); err != nil {
+		t.Fatal(err)
+	}
+
+	qr := "ORD:111-1111111-1111111;SKU:MRC-MR-0530;QTY:1"
+	scan, order, err := store.Verify(id, "111-1111111-1111111", qr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Pass || order.Status != verifyPending || order.Packed[0] != 0 {
+		t.Errorf("QR scan: pass %v, status %s, packed %d; want it ignored as a product", scan.Pass, order.Status, order.Packed[0])
+	}
+	if !order.VerifiedAt.IsZero() {
+		t.Error("order marked verified by its QR code")
+	}
+
+	scan, order, err = store.Verify(id, "111-1111111-1111111", "mrc-mr-0530", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scan.Pass || order.Status != verifyVerified || order.VerifiedAt.IsZero() {
+		t.Errorf("product scan: pass %v, status %s, verified at %v; want verified", scan.Pass, order.Status, order.VerifiedAt)
+	}
+
+	other := "ORD:222-2222222-2222222;SKU:MRC-MR-0530;QTY:1"
+	scan, order, err = store.Verify(id, "111-1111111-1111111", other, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Pass || order.Mismatches != 1 || !strings.Contains(scan.Message, "222-2222222-2222222") {
+		t.Errorf("other order's QR: pass %v, mismatches %d, message %q", scan.Pass, order.Mismatches, scan.Message)
+	}
+}
+
+func TestRunIDsAreUnique(t *testing.T) {
+	if a, b := newRunID("1700000000"), newRunID("1700000000"); a == b {
+		t.Errorf("two runs in the same second both got ID %s", a)
+	}
+}
@@ -0,0 +1,268 @@
+// handlers/page_order.go
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Page orderings selectable for overlay output
+const (
+	pageSortNone      = "none"
+	pageSortThickness = "thickness"
+	pageSortDimension = "dimension"
+	pageSortSKU       = "sku"
+	pageSortDate      = "date"
+)
+
+// overlayOptions controls how the stamped invoice PDF is assembled
+type overlayOptions struct {
+	PageSort   string // one of the pageSort constants
+	Separators bool   // insert a page before each group listing its orders
+	SplitBy    string // one of the split constants; output is then a ZIP
+	StampOn    string // one of the stamp constants: invoice, label or both pages
+	Barcode    bool   // Code128 of the order number beside each order's text
+	QRCode     bool   // QR code of order, SKU and dimension beside each line
+	Text       overlayText
+
+	// HighlightProblems marks pages with unmatched or several SKUs
+	// in red and puts a summary of them in front
+	HighlightProblems bool
+}
+
+// requestPageSort validates the pageSort form field
+func requestPageSort(value string) string {
+	switch value {
+	case pageSortThickness, pageSortDimension, pageSortSKU, pageSortDate:
+		return value
+	}
+	return pageSortNone
+}
+
+var orderDateRegex = regexp.MustCompile(`(?i)Order Date:\s*(\d{1,4}[./-]\d{1,2}[./-]\d{1,4})`)
+
+// Order date layouts seen on marketplace invoices, day first
+var orderDateLayouts = []string{"02.01.2006", "2.1.2006", "02/01/2006", "2/1/2006", "02-01-2006", "2-1-2006", "2006-01-02", "02.01.06"}
+
+// parseOrderDate parses an invoice order date; ok is false when the value
+// matches none of the known layouts
+func parseOrderDate(value string) (time.Time, bool) {
+	for _, layout := range orderDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// pageGroup is a run of source pages that share a sort key
+type pageGroup struct {
+	Label  string
+	Pages  []int
+	Orders []string
+	rank   pageRank
+}
+
+// pageRank orders groups: numeric value first, then label; pages without a
+// value go last
+type pageRank struct {
+	missing bool
+	value   float64
+	label   string
+}
+
+func (a pageRank) less(b pageRank) bool {
+	if a.missing != b.missing {
+		return b.missing
+	}
+	if a.value != b.value {
+		return a.value < b.value
+	}
+	return a.label < b.label
+}
+
+// pageKey returns the group label and rank for one page's lines under key
+func pageKey(lines []PDFOrderData, key string) (string, pageRank) {
+	var labels []string
+	seen := make(map[string]bool)
+	rank := pageRank{missing: true}
+
+	for _, line := range lines {
+		label := ""
+		switch key {
+		case pageSortThickness:
+			if line.ParsedThickness.MM > 0 {
+				label = line.Thickness
+				if rank.missing || line.ParsedThickness.MM < rank.value {
+					rank = pageRank{value: line.ParsedThickness.MM}
+				}
+			}
+		case pageSortDimension:
+			if !line.ParsedDimension.IsZero() {
+				label = line.Dimension
+				area := line.ParsedDimension.Area("mm")
+				if rank.missing || area < rank.value {
+					rank = pageRank{value: area}
+				}
+			}
+		case pageSortSKU:
+			switch {
+			case line.KitSKU != "":
+				label = line.KitSKU
+			case line.CatalogSKU != "":
+				label = line.CatalogSKU
+			default:
+				label = line.SKUID
+			}
+			rank.missing = false
+		case pageSortDate:
+			if date, ok := parseOrderDate(line.OrderDate); ok {
+				label = date.Format("02 Jan 2006")
+				rank = pageRank{value: float64(date.Unix())}
+			}
+		}
+
+		if label != "" && !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+
+	if len(labels) == 0 {
+		label := "Not in catalog"
+		if key == pageSortDate {
+			label = "No order date"
+		}
+		return label, pageRank{missing: true, label: label}
+	}
+	sort.Strings(labels)
+	rank.label = strings.Join(labels, " + ")
+	return rank.label, rank
+}
+
+// groupPages groups the pages 1..totalPages of the source PDF by key. Pages
+// keep their original order within a group; pages without orders form a
+// final "No orders" group.
+func groupPages(orders []PDFOrderData, totalPages int, key string) []pageGroup {
+	// Shipping labels travel with their order's invoice page
+	pageOrders := make(map[int][]PDFOrderData)
+	for _, order := range orders {
+		pageOrders[order.PageNumber] = append(pageOrders[order.PageNumber], order)
+		if order.LabelPage != 0 {
+			pageOrders[order.LabelPage] = append(pageOrders[order.LabelPage], order)
+		}
+	}
+
+	var groups []pageGroup
+	index := make(map[string]int)
+	var empty []int
+
+	for page := 1; page <= totalPages; page++ {
+		lines, hasOrders := pageOrders[page]
+		if !hasOrders {
+			empty = append(empty, page)
+			continue
+		}
+
+		label, rank := pageKey(lines, key)
+		i, exists := index[label]
+		if !exists {
+			i = len(groups)
+			index[label] = i
+			groups = append(groups, pageGroup{Label: label, rank: rank})
+		}
+		g := &groups[i]
+		g.Pages = append(g.Pages, page)
+		for _, line := range lines {
+			if len(g.Orders) == 0 || g.Orders[len(g.Orders)-1] != line.OrderNumber {
+				g.Orders = append(g.Orders, line.OrderNumber)
+			}
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].rank.less(groups[j].rank)
+	})
+
+	if len(empty) > 0 {
+		groups = append(groups, pageGroup{Label: "No orders", Pages: empty})
+	}
+
+	return groups
+}
+
+// reorderPDFPages writes the pages of inputPDF to outputPDF in group order,
+// optionally preceded by a separator page per group
+func reorderPDFPages(inputPDF string, groups []pageGroup, separators bool, outputPDF string) error {
+	args := []string{"A=" + inputPDF}
+
+	if separators {
+		separatorPDF := filepath.Join(os.TempDir(), fmt.Sprintf("separators_%d.pdf", time.Now().UnixNano()))
+		if err := createSeparatorPages(groups, separatorPDF); err != nil {
+			return fmt.Errorf("failed to create separator pages: %v", err)
+		}
+		defer os.Remove(separatorPDF)
+		args = append(args, "B="+separatorPDF)
+	}
+
+	args = append(args, "cat")
+	for i, g := range groups {
+		if separators {
+			args = append(args, fmt.Sprintf("B%d", i+1))
+		}
+		for _, page := range g.Pages {
+			args = append(args, fmt.Sprintf("A%d", page))
+		}
+	}
+	args = append(args, "output", outputPDF)
+
+	cmd := exec.Command("pdftk", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pdftk cat failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// createSeparatorPages writes one page per group with its label, page and
+// order counts and the order numbers
+func createSeparatorPages(groups []pageGroup, filename string) error {
+	pdf, fonts := newPDFDocument("P", "A4")
+	pdf.SetAutoPageBreak(false, 0)
+	tr := fonts.Encode
+
+	for i, g := range groups {
+		pdf.AddPage()
+
+		fonts.SetFont(pdf, "", 12)
+		pdf.SetXY(15, 20)
+		fonts.CellFormat(pdf, 0, 8, fmt.Sprintf("Group %d of %d", i+1, len(groups)), "", 1, "L", false, 0, "")
+
+		fonts.SetFont(pdf, "B", 28)
+		pdf.SetX(15)
+		fonts.MultiCell(pdf, 180, 12, tr(g.Label), "", "L", false)
+
+		fonts.SetFont(pdf, "", 14)
+		pdf.SetX(15)
+		fonts.CellFormat(pdf, 0, 10, fmt.Sprintf("%d orders on %d pages", len(g.Orders), len(g.Pages)), "", 1, "L", false, 0, "")
+
+		pdf.Ln(4)
+		fonts.SetFont(pdf, "", 10)
+		for j, order := range g.Orders {
+			if pdf.GetY() > 280 {
+				pdf.SetX(15)
+				fonts.CellFormat(pdf, 0, 6, fmt.Sprintf("... and %d more", len(g.Orders)-j), "", 1, "L", false, 0, "")
+				break
+			}
+			pdf.SetX(15)
+			fonts.CellFormat(pdf, 0, 6, order, "", 1, "L", false, 0, "")
+		}
+	}
+
+	return pdf.OutputFileAndClose(filename)
+}
@@ -0,0 +1,116 @@
+// handlers/page_types.go
+package handlers
+
+import (
+	"regexp"
+)
+
+// Kinds of page in an uploaded PDF. Combined "label + invoice" downloads
+// alternate shipping label and invoice pages.
+const (
+	pageTypeInvoice = "invoice"
+	pageTypeLabel   = "label"
+	pageTypeOther   = "other"
+)
+
+// Pages overlay annotations can be stamped on
+const (
+	stampInvoice = "invoice"
+	stampLabel   = "label"
+	stampBoth    = "both"
+)
+
+var (
+	invoiceMarkerRegex = regexp.MustCompile(`(?i)(tax invoice|invoice number|invoice no\b|bill of supply|order number:)`)
+	labelMarkerRegex   = regexp.MustCompile(`(?i)(ship to\b|deliver to\b|\bawb\b|tracking (id|no|number)|shipment id|return address|\bprepaid\b|cod collectible|amazon shipping|easy ship)`)
+	orderIDRegex       = regexp.MustCompile(`\b\d{3}-\d{7}-\d{7}\b`)
+)
+
+// requestStampOn validates the stampOn form field
+func requestStampOn(value string) string {
+	switch value {
+	case stampLabel, stampBoth:
+		return value
+	}
+	return stampInvoice
+}
+
+// classifyPage tells invoice pages from shipping labels by their wording.
+// Invoices also print the delivery address, so invoice markers win.
+func classifyPage(text string) string {
+	switch {
+	case invoiceMarkerRegex.MatchString(text):
+		return pageTypeInvoice
+	case labelMarkerRegex.MatchString(text):
+		return pageTypeLabel
+	}
+	return pageTypeOther
+}
+
+// linkLabelPages sets LabelPage on the orders that have a shipping label in
+// pages. A label printing an order ID belongs to that order; otherwise it
+// belongs to the invoice page right after it, as in Amazon's label + invoice
+// downloads, or failing that the one right before it.
+func linkLabelPages(orders []PDFOrderData, pages []pdfPage) {
+	labelPages := make(map[int]bool)
+	claimed := make(map[int]bool)
+	for _, page := range pages {
+		if page.Type != pageTypeLabel {
+			continue
+		}
+		labelPages[page.Number] = true
+
+		for _, id := range orderIDRegex.FindAllString(page.Text, -1) {
+			for i := range orders {
+				if orders[i].OrderNumber == id && orders[i].LabelPage == 0 {
+					orders[i].LabelPage = page.Number
+					claimed[page.Number] = true
+				}
+			}
+		}
+	}
+
+	// Remaining labels go to a neighbouring invoice page, all of whose
+	// orders share the label
+	for _, offset := range []int{-1, 1} {
+		byPage := make(map[int]bool)
+		for i := range orders {
+			label := orders[i].PageNumber + offset
+			if orders[i].LabelPage != 0 || !labelPages[label] || (claimed[label] && !byPage[label]) {
+				continue
+			}
+			orders[i].LabelPage = label
+			claimed[label] = true
+			byPage[label] = true
+		}
+	}
+
+	// Items continued on later pages share their order's label
+	orderLabel := make(map[string]int)
+	for _, order := range orders {
+		if order.LabelPage != 0 && orderLabel[order.OrderNumber] == 0 {
+			orderLabel[order.OrderNumber] = order.LabelPage
+		}
+	}
+	for i := range orders {
+		if orders[i].LabelPage == 0 {
+			orders[i].LabelPage = orderLabel[orders[i].OrderNumber]
+		}
+	}
+}
+
+// stampPageOrders groups orders by the pages their annotations are stamped
+// on. Orders without a label page are stamped on their invoice page.
+func stampPageOrders(orders []PDFOrderData, stampOn string) map[int][]PDFOrderData {
+	pageOrders := make(map[int][]PDFOrderData)
+	for _, order := range orders {
+		onLabel := order.LabelPage != 0 && stampOn != stampInvoice
+		if !onLabel || stampOn == stampBoth {
+			pageOrders[order.PageNumber] = append(pageOrders[order.PageNumber], order)
+		}
+		if onLabel {
+			pageOrders[order.LabelPage] = append(pageOrders[order.LabelPage], order)
+		}
+	}
+	return pageOrders
+}
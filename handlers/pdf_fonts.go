@@ -0,0 +1,449 @@
+// handlers/pdf_fonts.go
+package handlers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// PDFFontPath and PDFBoldFontPath are the TrueType fonts embedded in
+// generated PDFs. They are set from the -pdf-font and -pdf-font-bold command
+// line flags; empty paths use the DejaVu Sans files bundled in ./fonts.
+// PDFFallbackFontPath (-pdf-font-fallback) draws the characters the main font
+// has no glyph for, by default the bundled Noto Sans Devanagari for Hindi.
+var (
+	PDFFontPath         string
+	PDFBoldFontPath     string
+	PDFFallbackFontPath string
+)
+
+const (
+	bundledFontPath             = "./fonts/DejaVuSans.ttf"
+	bundledBoldFontPath         = "./fonts/DejaVuSans-Bold.ttf"
+	bundledFallbackFontPath     = "./fonts/NotoSansDevanagari-Regular.ttf"
+	bundledFallbackBoldFontPath = "./fonts/NotoSansDevanagari-Bold.ttf"
+
+	// unicodeFontFamily and fallbackFontFamily are the names the TrueType
+	// fonts are registered under
+	unicodeFontFamily  = "Unicode"
+	fallbackFontFamily = "UnicodeFallback"
+
+	// devanagariSample is checked against the fonts when they are loaded
+	devanagariSample = "हिंदी"
+)
+
+// fontFiles is the TrueType font data for generated PDFs
+type fontFiles struct {
+	regular, bold             []byte
+	fallback, fallbackBold    []byte
+	regularCmap, fallbackCmap []byte
+	unavailable               bool
+}
+
+// utf8Font is the configured fonts, read once
+var utf8Font struct {
+	once  sync.Once
+	files *fontFiles
+}
+
+// loadUTF8Font reads the configured or bundled fonts
+func loadUTF8Font() {
+	regularPath, boldPath := PDFFontPath, PDFBoldFontPath
+	if regularPath == "" {
+		regularPath, boldPath = bundledFontPath, bundledBoldFontPath
+	}
+	fallbackPath, fallbackBoldPath := PDFFallbackFontPath, ""
+	if fallbackPath == "" {
+		fallbackPath, fallbackBoldPath = bundledFallbackFontPath, bundledFallbackBoldFontPath
+	}
+	utf8Font.files = readFontFiles(regularPath, boldPath, fallbackPath, fallbackBoldPath)
+}
+
+// readFontFiles reads the main and fallback fonts. A missing bold face falls
+// back to the regular one; a missing regular face disables embedding and PDFs
+// fall back to the core Latin-1 fonts. The fallback font is optional.
+func readFontFiles(regularPath, boldPath, fallbackPath, fallbackBoldPath string) *fontFiles {
+	files := &fontFiles{}
+
+	regular, err := os.ReadFile(regularPath)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to read PDF font %s, non-Latin text will not render: %v\n", regularPath, err)
+		files.unavailable = true
+		return files
+	}
+	files.regular, files.bold = regular, readBoldFont(boldPath, regular)
+	files.regularCmap = fontUnicodeCmap(regular)
+
+	if fallback, err := os.ReadFile(fallbackPath); err == nil {
+		files.fallback, files.fallbackBold = fallback, readBoldFont(fallbackBoldPath, fallback)
+		files.fallbackCmap = fontUnicodeCmap(fallback)
+	} else if !os.IsNotExist(err) || fallbackPath != bundledFallbackFontPath {
+		fmt.Printf("⚠️  Failed to read fallback PDF font %s: %v\n", fallbackPath, err)
+	}
+
+	if !files.covers(devanagariSample) {
+		fmt.Printf("⚠️  No PDF font has Devanagari glyphs, Hindi text will print as blanks; add %s or use -pdf-font-fallback\n", bundledFallbackFontPath)
+	}
+	return files
+}
+
+// readBoldFont reads a bold face, using regular when there is none
+func readBoldFont(path string, regular []byte) []byte {
+	if path == "" {
+		return regular
+	}
+	bold, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to read bold PDF font %s, using regular: %v\n", path, err)
+		return regular
+	}
+	return bold
+}
+
+// covers reports whether the main or fallback font has a glyph for every
+// non-space character of text
+func (files *fontFiles) covers(text string) bool {
+	for _, r := range text {
+		if r > ' ' && !cmapCovers(files.regularCmap, r) && !cmapCovers(files.fallbackCmap, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// fontCovers reports whether the TrueType font has a glyph for every
+// non-space character of text, reading the font's Unicode cmap (format 4 or
+// 12). A font it cannot read is assumed to cover everything.
+func fontCovers(font []byte, text string) bool {
+	cmap := fontUnicodeCmap(font)
+	if cmap == nil {
+		return true
+	}
+	for _, r := range text {
+		if r > ' ' && !cmapCovers(cmap, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// cmapCovers reports whether a cmap subtable maps r to a glyph; a nil table
+// covers nothing
+func cmapCovers(cmap []byte, r rune) bool {
+	return cmap != nil && cmapGlyph(cmap, r) != 0
+}
+
+// fontUnicodeCmap returns the font's best Unicode cmap subtable, preferring
+// full-range format 12 to BMP-only format 4, or nil
+func fontUnicodeCmap(font []byte) []byte {
+	u16 := func(b []byte, off int) int { return int(binary.BigEndian.Uint16(b[off:])) }
+	u32 := func(b []byte, off int) int { return int(binary.BigEndian.Uint32(b[off:])) }
+
+	if len(font) < 12 {
+		return nil
+	}
+	numTables := u16(font, 4)
+	for i := 0; i < numTables; i++ {
+		entry := 12 + 16*i
+		if entry+16 > len(font) || string(font[entry:entry+4]) != "cmap" {
+			continue
+		}
+		cmap := font[min(u32(font, entry+8), len(font)):]
+		if len(cmap) < 4 {
+			return nil
+		}
+
+		var best []byte
+		for j := 0; j < u16(cmap, 2); j++ {
+			record := 4 + 8*j
+			if record+8 > len(cmap) {
+				break
+			}
+			platform, encoding := u16(cmap, record), u16(cmap, record+2)
+			if platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10)) {
+				continue
+			}
+			offset := u32(cmap, record+4)
+			if offset+2 > len(cmap) {
+				continue
+			}
+			switch u16(cmap, offset) {
+			case 12:
+				return cmap[offset:]
+			case 4:
+				best = cmap[offset:]
+			}
+		}
+		return best
+	}
+	return nil
+}
+
+// cmapGlyph returns the glyph index of r in a format 4 or 12 cmap subtable,
+// 0 when the font has no glyph for it
+func cmapGlyph(table []byte, r rune) int {
+	u16 := func(off int) int {
+		if off+2 > len(table) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(table[off:]))
+	}
+	u32 := func(off int) int {
+		if off+4 > len(table) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint32(table[off:]))
+	}
+	c := int(r)
+
+	if u16(0) == 12 {
+		for g := 0; g < u32(12); g++ {
+			group := 16 + 12*g
+			if start, end := u32(group), u32(group+4); c >= start && c <= end {
+				return u32(group+8) + c - start
+			}
+		}
+		return 0
+	}
+
+	segments := u16(6) / 2
+	ends, starts := 14, 16+2*segments
+	deltas, rangeOffsets := starts+2*segments, starts+4*segments
+	for s := 0; s < segments; s++ {
+		start, end := u16(starts+2*s), u16(ends+2*s)
+		if c < start || c > end {
+			continue
+		}
+		delta, rangeOffset := u16(deltas+2*s), u16(rangeOffsets+2*s)
+		if rangeOffset == 0 {
+			return (c + delta) & 0xFFFF
+		}
+		glyph := u16(rangeOffsets + 2*s + rangeOffset + 2*(c-start))
+		if glyph == 0 {
+			return 0
+		}
+		return (glyph + delta) & 0xFFFF
+	}
+	return 0
+}
+
+// pdfFonts is the default font family of a generated PDF and the encoding
+// its text needs. With a fallback font, text is drawn through its methods so
+// that characters the main font lacks are set in the fallback; the current
+// style is tracked for that, so fonts are selected with SetFont.
+type pdfFonts struct {
+	Family string
+	Encode func(string) string
+
+	files    *fontFiles // nil for core fonts
+	style    string
+	fallback bool
+}
+
+// newPDFDocument creates a millimetre based gofpdf document with the UTF-8
+// TrueType font registered as its default family
+func newPDFDocument(orientation, size string) (*gofpdf.Fpdf, *pdfFonts) {
+	utf8Font.once.Do(loadUTF8Font)
+	return newPDFDocumentWithFiles(orientation, size, utf8Font.files)
+}
+
+func newPDFDocumentWithFiles(orientation, size string, files *fontFiles) (*gofpdf.Fpdf, *pdfFonts) {
+	pdf := gofpdf.New(orientation, "mm", size, "")
+	if files.unavailable {
+		return pdf, &pdfFonts{Family: "Arial", Encode: pdf.UnicodeTranslatorFromDescriptor("")}
+	}
+
+	pdf.AddUTF8FontFromBytes(unicodeFontFamily, "", files.regular)
+	pdf.AddUTF8FontFromBytes(unicodeFontFamily, "B", files.bold)
+	fonts := &pdfFonts{Family: unicodeFontFamily, Encode: func(s string) string { return s }, files: files}
+	if files.fallback != nil {
+		pdf.AddUTF8FontFromBytes(fallbackFontFamily, "", files.fallback)
+		pdf.AddUTF8FontFromBytes(fallbackFontFamily, "B", files.fallbackBold)
+		fonts.fallback = true
+	}
+	return pdf, fonts
+}
+
+// newPDFDocumentWithFont is newPDFDocument for text in family, where "" is
+// the default. Core fonts such as Arial only cover Latin-1; the TrueType
+// font is then left out, as gofpdf embeds every registered font.
+func newPDFDocumentWithFont(orientation, size, family string) (*gofpdf.Fpdf, *pdfFonts) {
+	if family == "" {
+		return newPDFDocument(orientation, size)
+	}
+	pdf := gofpdf.New(orientation, "mm", size, "")
+	return pdf, &pdfFonts{Family: family, Encode: pdf.UnicodeTranslatorFromDescriptor("")}
+}
+
+// SetFont selects the document's family in style ("" or "B") and size in
+// points
+func (f *pdfFonts) SetFont(pdf *gofpdf.Fpdf, style string, size float64) {
+	f.style = style
+	pdf.SetFont(f.Family, style, size)
+}
+
+// Covers reports whether the document's fonts can draw every character of
+// text
+func (f *pdfFonts) Covers(text string) bool {
+	return f.files == nil || f.files.covers(text)
+}
+
+// fontRun is a stretch of text drawn in one family
+type fontRun struct {
+	family string
+	text   string
+}
+
+// runs splits text into stretches for the main and fallback fonts. Spaces
+// stay with the run they are in, so words are not split. It returns nil
+// when the main font can draw all of text.
+func (f *pdfFonts) runs(text string) []fontRun {
+	if !f.fallback {
+		return nil
+	}
+
+	var runs []fontRun
+	var current strings.Builder
+	family := unicodeFontFamily
+	needed := false
+	for _, r := range text {
+		next := family
+		if r > ' ' {
+			next = unicodeFontFamily
+			if !cmapCovers(f.files.regularCmap, r) && cmapCovers(f.files.fallbackCmap, r) {
+				next = fallbackFontFamily
+				needed = true
+			}
+		}
+		if next != family && current.Len() > 0 {
+			runs = append(runs, fontRun{family: family, text: current.String()})
+			current.Reset()
+		}
+		family = next
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		runs = append(runs, fontRun{family: family, text: current.String()})
+	}
+
+	if !needed {
+		return nil
+	}
+	return runs
+}
+
+// StringWidth is gofpdf's GetStringWidth, measuring each run in its font
+func (f *pdfFonts) StringWidth(pdf *gofpdf.Fpdf, text string) float64 {
+	runs := f.runs(text)
+	if runs == nil {
+		return pdf.GetStringWidth(text)
+	}
+
+	size, _ := pdf.GetFontSize()
+	width := 0.0
+	for _, run := range runs {
+		pdf.SetFont(run.family, f.style, size)
+		width += pdf.GetStringWidth(run.text)
+	}
+	pdf.SetFont(f.Family, f.style, size)
+	return width
+}
+
+// CellFormat is gofpdf's CellFormat, drawing characters the main font lacks
+// in the fallback font
+func (f *pdfFonts) CellFormat(pdf *gofpdf.Fpdf, w, h float64, text, border string, ln int, align string, fill bool, link int, linkStr string) {
+	runs := f.runs(text)
+	if runs == nil {
+		pdf.CellFormat(w, h, text, border, ln, align, fill, link, linkStr)
+		return
+	}
+
+	x, y := pdf.GetXY()
+	left, _, right, _ := pdf.GetMargins()
+	if w == 0 {
+		pageWidth, _ := pdf.GetPageSize()
+		w = pageWidth - right - x
+	}
+
+	// Border and fill first, then the runs left to right from where the
+	// text starts under the horizontal alignment
+	pdf.CellFormat(w, h, "", border, 0, "", fill, link, linkStr)
+
+	margin := pdf.GetCellMargin()
+	textWidth := f.StringWidth(pdf, text)
+	offset := margin
+	switch {
+	case strings.Contains(align, "R"):
+		offset = w - margin - textWidth
+	case strings.Contains(align, "C"):
+		offset = (w - textWidth) / 2
+	}
+	vertical := strings.NewReplacer("L", "", "C", "", "R", "").Replace(align)
+
+	size, _ := pdf.GetFontSize()
+	pdf.SetCellMargin(0)
+	pdf.SetXY(x+offset, y)
+	for _, run := range runs {
+		pdf.SetFont(run.family, f.style, size)
+		pdf.CellFormat(pdf.GetStringWidth(run.text), h, run.text, "", 0, "L"+vertical, false, 0, "")
+	}
+	pdf.SetCellMargin(margin)
+	pdf.SetFont(f.Family, f.style, size)
+
+	switch ln {
+	case 0:
+		pdf.SetXY(x+w, y)
+	case 1:
+		pdf.SetXY(left, y+h)
+	default:
+		pdf.SetXY(x, y+h)
+	}
+}
+
+// MultiCell is gofpdf's MultiCell for unbordered text, wrapping at spaces
+// and drawing characters the main font lacks in the fallback font
+func (f *pdfFonts) MultiCell(pdf *gofpdf.Fpdf, w, h float64, text, border, align string, fill bool) {
+	if f.runs(text) == nil {
+		pdf.MultiCell(w, h, text, border, align, fill)
+		return
+	}
+
+	left, _, right, _ := pdf.GetMargins()
+	if w == 0 {
+		pageWidth, _ := pdf.GetPageSize()
+		w = pageWidth - right - pdf.GetX()
+	}
+	for _, line := range f.SplitLines(pdf, text, w-2*pdf.GetCellMargin()) {
+		f.CellFormat(pdf, w, h, line, border, 2, align, fill, 0, "")
+	}
+	pdf.SetX(left)
+}
+
+// SplitLines wraps text to width at spaces in the current font. Unlike
+// gofpdf's SplitLines it measures whole strings, so multi-byte UTF-8 text is
+// never cut mid-character.
+func (f *pdfFonts) SplitLines(pdf *gofpdf.Fpdf, text string, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line == "" {
+			line = word
+			continue
+		}
+		if f.StringWidth(pdf, line+" "+word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
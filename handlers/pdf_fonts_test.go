@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"testing"
+)
+
+const hindiSample = "राम कुमार - लकड़ी का फ्रेम"
+
+func TestBundledFontLacksDevanagari(t *testing.T) {
+	font, err := os.ReadFile("../" + bundledFontPath)
+	if err != nil {
+		t.Skipf("bundled font not available: %v", err)
+	}
+
+	// Hence the fallback font
+	if !fontCovers(font, "MRC-MR-0530 24×36 in²") {
+		t.Error("bundled font reported missing Latin glyphs")
+	}
+	if fontCovers(font, hindiSample) {
+		t.Error("bundled font reported as covering Devanagari")
+	}
+}
+
+func TestDefaultFontsRenderHindi(t *testing.T) {
+	files := readFontFiles("../"+bundledFontPath, "../"+bundledBoldFontPath,
+		"../"+bundledFallbackFontPath, "../"+bundledFallbackBoldFontPath)
+	if files.unavailable {
+		t.Skip("bundled font not available")
+	}
+	if files.fallback == nil {
+		t.Skipf("%s is not present; run ./run.sh to download it", bundledFallbackFontPath)
+	}
+
+	if !files.covers(hindiSample) {
+		t.Fatalf("default fonts do not cover %q", hindiSample)
+	}
+
+	pdf, fonts := newPDFDocumentWithFiles("P", "A4", files)
+	pdf.AddPage()
+	fonts.SetFont(pdf, "B", 12)
+	if fonts.runs(hindiSample) == nil {
+		t.Error("Hindi text not drawn with the fallback font")
+	}
+	fonts.CellFormat(pdf, 0, 10, hindiSample, "", 1, "L", false, 0, "")
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		t.Fatalf("rendering Hindi text failed: %v", err)
+	}
+}
+
+// latinOnlyCmap is a format 4 cmap subtable mapping U+0020..U+007E only
+func latinOnlyCmap() []byte {
+	words := []uint16{
+		4, 32, 0, // format, length, language
+		4, 0, 0, 0, // segCountX2, searchRange, entrySelector, rangeShift
+		0x7E, 0xFFFF, // endCode
+		0,            // reservedPad
+		0x20, 0xFFFF, // startCode
+		0xFFE1, 1, // idDelta: glyph = code - 31
+		0, 0, // idRangeOffset
+	}
+	table := make([]byte, 2*len(words))
+	for i, w := range words {
+		binary.BigEndian.PutUint16(table[2*i:], w)
+	}
+	return table
+}
+
+func TestFallbackFontDrawsMissingGlyphs(t *testing.T) {
+	font, err := os.ReadFile("../" + bundledFontPath)
+	if err != nil {
+		t.Skipf("bundled font not available: %v", err)
+	}
+
+	// A Latin-only main font with DejaVu Sans as its fallback
+	files := &fontFiles{
+		regular: font, bold: font, fallback: font, fallbackBold: font,
+		regularCmap: latinOnlyCmap(), fallbackCmap: fontUnicodeCmap(font),
+	}
+	pdf, fonts := newPDFDocumentWithFiles("P", "A4", files)
+	pdf.AddPage()
+	fonts.SetFont(pdf, "", 12)
+
+	text := "24×36 in"
+	runs := fonts.runs(text)
+	want := []fontRun{{unicodeFontFamily, "24"}, {fallbackFontFamily, "×"}, {unicodeFontFamily, "36 in"}}
+	if len(runs) != len(want) {
+		t.Fatalf("runs = %+v, want %+v", runs, want)
+	}
+	for i := range want {
+		if runs[i] != want[i] {
+			t.Errorf("run %d = %+v, want %+v", i, runs[i], want[i])
+		}
+	}
+	if fonts.runs("24x36 in") != nil {
+		t.Error("text the main font covers was split into runs")
+	}
+
+	// Same font data in both families, so the width is unchanged
+	if got, want := fonts.StringWidth(pdf, text), pdf.GetStringWidth(text); math.Abs(got-want) > 1e-9 {
+		t.Errorf("StringWidth = %v, want %v", got, want)
+	}
+
+	pdf.SetXY(20, 30)
+	fonts.CellFormat(pdf, 50, 8, text, "1", 0, "C", false, 0, "")
+	if x, y := pdf.GetXY(); x != 70 || y != 30 {
+		t.Errorf("position after cell = %v, %v; want 70, 30", x, y)
+	}
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		t.Fatal(err)
+	}
+}
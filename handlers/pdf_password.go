@@ -0,0 +1,79 @@
+// handlers/pdf_password.go
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// DefaultPDFPassword is used when a request does not supply its own
+// password. It is set from the -pdf-password command line flag.
+var DefaultPDFPassword string
+
+var (
+	errPDFPasswordRequired  = errors.New("PDF is password protected; please provide the password")
+	errPDFPasswordIncorrect = errors.New("incorrect password for the encrypted PDF")
+)
+
+// checkPDFAccess runs pdfinfo up front so that encrypted PDFs fail with a
+// clear message instead of a bare pdftotext exit status
+func checkPDFAccess(pdfPath, password string) error {
+	if _, err := exec.LookPath("pdfinfo"); err != nil {
+		// Nothing to check with; let extraction report its own error
+		return nil
+	}
+
+	args := append(popplerPasswordArgs(password), pdfPath)
+	cmd := exec.Command("pdfinfo", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(strings.ToLower(stderr.String()), "incorrect password") {
+			if password == "" {
+				return errPDFPasswordRequired
+			}
+			return errPDFPasswordIncorrect
+		}
+		return fmt.Errorf("pdfinfo failed: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// isPDFPasswordError reports whether err is one of the password errors,
+// which are the caller's fault rather than the server's
+func isPDFPasswordError(err error) bool {
+	return errors.Is(err, errPDFPasswordRequired) || errors.Is(err, errPDFPasswordIncorrect)
+}
+
+// popplerPasswordArgs returns the pdftotext/pdfinfo arguments for password.
+// The same value is offered as owner and user password since users rarely
+// know which one they were given.
+func popplerPasswordArgs(password string) []string {
+	if password == "" {
+		return nil
+	}
+	return []string{"-opw", password, "-upw", password}
+}
+
+// pdftkPasswordArgs returns the pdftk arguments that unlock the preceding
+// input file
+func pdftkPasswordArgs(password string) []string {
+	if password == "" {
+		return nil
+	}
+	return []string{"input_pw", password}
+}
+
+// requestPDFPassword returns the password form field, falling back to the
+// server-wide default
+func requestPDFPassword(formValue string) string {
+	if formValue != "" {
+		return formValue
+	}
+	return DefaultPDFPassword
+}
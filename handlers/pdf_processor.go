@@ -5,11 +5,13 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,17 +20,134 @@ import (
 )
 
 type PDFOrderData struct {
-	OrderNumber string
-	SKUID       string
-	Thickness   string
-	Dimension   string
-	PageNumber  int
+	OrderNumber   string
+	OrderDate     string // as printed on the invoice, if found
+	SKUID         string
+	Thickness     string
+	Dimension     string
+	PageNumber    int
+	LabelPage     int     // page of the order's shipping label, 0 if none
+	OCR           bool    // page text came from OCR rather than the text layer
+	OCRConfidence float64 // mean OCR word confidence, 0-100
+	MatchType     string  // exact, normalised, fuzzy, alias or none
+	SuggestedSKU  string
+	CatalogSKU    string // resolved catalog SKU; SKUID keeps the identifier as printed
+	KitSKU        string // kit this line was expanded from, if any
+	Quantity      int
+	Weight        float64 // kg per piece
+	WeightDerived bool
+	PackedSize    PackedSize
+	BinLocation   string
+	Custom        map[string]string // catalog columns after Bin Location, by header
+
+	ParsedThickness Thickness
+	ParsedDimension Dimension
+}
+
+// lowOCRConfidence is the mean word confidence below which OCR text is
+// flagged as unreliable
+const lowOCRConfidence = 60.0
+
+// textSource reports where the order's page text came from, flagging OCR
+// text of low confidence
+func (o PDFOrderData) textSource() string {
+	switch {
+	case o.OCR && o.OCRConfidence < lowOCRConfidence:
+		return "OCR (low confidence)"
+	case o.OCR:
+		return "OCR"
+	}
+	return "Text"
+}
+
+func (o PDFOrderData) ocrConfidenceString() string {
+	if !o.OCR {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", o.OCRConfidence)
+}
+
+// lineWeight is the weight of all pieces on the line in kg
+func (o PDFOrderData) lineWeight() float64 {
+	quantity := o.Quantity
+	if quantity < 1 {
+		quantity = 1
+	}
+	return o.Weight * float64(quantity)
+}
+
+func (o PDFOrderData) weightSource() string {
+	switch {
+	case o.CatalogSKU == "":
+		return ""
+	case o.WeightDerived:
+		return "Derived"
+	case o.Weight == 0:
+		return "Missing"
+	}
+	return "Catalog"
+}
+
+// pdfPage is the extracted text of one page of the source PDF
+type pdfPage struct {
+	Number        int
+	Text          string
+	OCR           bool
+	OCRConfidence float64
+	Type          string // invoice, label or other; set by processPDFPages
 }
 
 type PDFSKUMapping struct {
-	SKU       string
-	Thickness string
-	Dimension string
+	SKU           string
+	Thickness     string
+	Dimension     string
+	Weight        float64 // kg per piece
+	WeightDerived bool
+	PackedSize    PackedSize
+	BinLocation   string
+	Custom        map[string]string
+
+	ParsedThickness Thickness
+	ParsedDimension Dimension
+}
+
+// pdfCatalog is everything loaded from the mapping upload that is needed to
+// resolve invoice lines
+type pdfCatalog struct {
+	SKUs    map[string]PDFSKUMapping
+	Aliases map[string]string         // alias -> catalog or kit SKU
+	Kits    map[string][]kitComponent // kit SKU -> components
+	matcher *skuMatcher
+}
+
+func newPDFCatalog(skuMap map[string]PDFSKUMapping, aliases map[string]string, kits map[string][]kitComponent) *pdfCatalog {
+	skus := make([]string, 0, len(skuMap)+len(kits))
+	for sku := range skuMap {
+		skus = append(skus, sku)
+	}
+	for kit := range kits {
+		if _, exists := skuMap[kit]; !exists {
+			skus = append(skus, kit)
+		}
+	}
+
+	matcher := newSKUMatcher(skus, aliases)
+
+	// Kit SKUs rarely follow the MRC-MR pattern, so look for them verbatim
+	var kitSKUs []string
+	for kit := range kits {
+		kitSKUs = append(kitSKUs, kit)
+	}
+	if len(kitSKUs) > 0 {
+		matcher.addTextIdentifiers(kitSKUs)
+	}
+
+	return &pdfCatalog{
+		SKUs:    skuMap,
+		Aliases: aliases,
+		Kits:    kits,
+		matcher: matcher,
+	}
 }
 
 func ProcessPDFHandler(w http.ResponseWriter, r *http.Request) {
@@ -60,9 +179,40 @@ func ProcessPDFHandler(w http.ResponseWriter, r *http.Request) {
 	defer mappingFile.Close()
 
 	outputMode := r.FormValue("outputMode")
-	if outputMode != "csv" && outputMode != "overlay" {
+	if outputMode != "csv" && outputMode != "xlsx" && outputMode != "overlay" && outputMode != "cutlist" && outputMode != "nesting" && outputMode != "picklist" {
 		outputMode = "csv" // default
 	}
+	cutListFormat := requestCutListFormat(r.FormValue("cutListFormat"))
+	overlayOpts := overlayOptions{
+		PageSort:          requestPageSort(r.FormValue("pageSort")),
+		Separators:        r.FormValue("separatorPages") == "on",
+		SplitBy:           requestSplitBy(r.FormValue("splitBy")),
+		Barcode:           r.FormValue("orderBarcode") == "on",
+		QRCode:            r.FormValue("qrCode") == "on",
+		HighlightProblems: r.FormValue("highlightProblems") == "on",
+		StampOn:           requestStampOn(r.FormValue("stampOn")),
+	}
+	overlayOpts.Text, err = requestOverlayText(r)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	password := requestPDFPassword(r.FormValue("password"))
+	dimensionUnit := requestDimensionUnit(r.FormValue("dimensionUnit"))
+	includeShipments := r.FormValue("shipmentSummary") == "on"
+	if includeShipments && outputMode != "csv" && outputMode != "xlsx" {
+		writeJSONError(w, "Shipment weights are only available with CSV or XLSX output", http.StatusBadRequest)
+		return
+	}
+	includeDiagnostics := r.FormValue("diagnostics") == "on"
+	previewOrders := r.FormValue("preview") == "on"
+
+	volumetricDivisor, err := requestVolumetricDivisor(r.FormValue("volumetricDivisor"))
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Save uploaded files
 	timestamp := fmt.Sprintf("%d", time.Now().Unix())
@@ -90,38 +240,176 @@ func ProcessPDFHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, "Failed to load SKU mapping: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	for sku, mapping := range skuMap {
+		mapping.Dimension = formatDimension(mapping.Dimension, mapping.ParsedDimension, dimensionUnit)
+		skuMap[sku] = mapping
+	}
 
-	// Extract text from PDF
-	textContent, err := extractTextFromPDF(pdfPath)
+	// Marketplace aliases from the upload's Aliases sheet and the alias table
+	aliases, err := loadRequestAliases(mappingPath)
 	if err != nil {
 		os.Remove(pdfPath)
 		os.Remove(mappingPath)
-		writeJSONError(w, "Failed to extract text from PDF: "+err.Error(), http.StatusInternalServerError)
+		writeJSONError(w, "Failed to load SKU aliases: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Process the extracted text
-	orderData, err := processPDFText(textContent, skuMap)
+	// Kit definitions from the upload's Kits sheet
+	kits, err := loadCatalogKits(mappingPath, skuMap)
+	if err != nil {
+		os.Remove(pdfPath)
+		os.Remove(mappingPath)
+		writeJSONError(w, "Failed to load kits: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	catalog := newPDFCatalog(skuMap, aliases, kits)
+
+	// Stock sheet and roll sizes from the upload's Stock sheet. Only nesting
+	// uses them, so other modes run on with a warning if the sheet is bad.
+	stock, err := loadCatalogStock(mappingPath)
+	if err != nil && outputMode != "nesting" {
+		fmt.Printf("⚠️  Ignoring %s sheet of %s: %v\n", stockSheetName, mappingHeader.Filename, err)
+		stock, err = nil, nil
+	}
+	if err == nil && outputMode == "nesting" && len(stock) == 0 {
+		err = fmt.Errorf("nesting needs a %s sheet in the mapping file", stockSheetName)
+	}
 	if err != nil {
 		os.Remove(pdfPath)
 		os.Remove(mappingPath)
-		writeJSONError(w, "Failed to process PDF text: "+err.Error(), http.StatusInternalServerError)
+		writeJSONError(w, "Failed to load stock sizes: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Detect encrypted PDFs before extraction
+	if err := checkPDFAccess(pdfPath, password); err != nil {
+		os.Remove(pdfPath)
+		os.Remove(mappingPath)
+		status := http.StatusInternalServerError
+		if isPDFPasswordError(err) {
+			status = http.StatusBadRequest
+		}
+		writeJSONError(w, err.Error(), status)
 		return
 	}
 
+	var orderData []PDFOrderData
+	var diagnostics *ExtractionDiagnostics
+	var downloads []DownloadLink
+
+	if confirmedLines := r.FormValue("confirmedLines"); confirmedLines != "" {
+		// Reviewed rows from the preview replace extraction
+		orderData, err = confirmedOrders(confirmedLines, catalog)
+		if err != nil {
+			os.Remove(pdfPath)
+			os.Remove(mappingPath)
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		// Extract text from PDF, falling back to OCR for image-only pages
+		pages, err := extractPDFPages(pdfPath, password, pdfOCREngine)
+		if err != nil {
+			os.Remove(pdfPath)
+			os.Remove(mappingPath)
+			writeJSONError(w, "Failed to extract text from PDF: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Process the extracted text
+		orderData, diagnostics, err = processPDFPages(pages, catalog)
+
+		if includeDiagnostics {
+			diagnosticsName := timestamp + "_diagnostics.json"
+			if err := writeDiagnosticsJSON(diagnostics, filepath.Join(outputDir, diagnosticsName)); err != nil {
+				fmt.Printf("Failed to save diagnostics: %v\n", err)
+			} else {
+				downloads = append(downloads, DownloadLink{Label: "Extraction diagnostics (JSON)", URL: "/outputs/" + diagnosticsName})
+			}
+		} else {
+			diagnostics = nil
+		}
+
+		if err != nil {
+			os.Remove(pdfPath)
+			os.Remove(mappingPath)
+			writeJSONFailure(w, ProcessResult{
+				Success:     false,
+				Message:     "Failed to process PDF text: " + err.Error(),
+				Downloads:   downloads,
+				Diagnostics: diagnostics,
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		if previewOrders {
+			os.Remove(pdfPath)
+			os.Remove(mappingPath)
+			writeJSONResult(w, ProcessResult{
+				Success:     true,
+				Message:     fmt.Sprintf("%d lines extracted. Check them and confirm to create the output.", len(orderData)),
+				Downloads:   downloads,
+				Diagnostics: diagnostics,
+				Preview:     newOrderPreview(orderData, catalog),
+				Unmatched:   unmatchedOrderSKUs(orderData, catalog),
+			})
+			return
+		}
+	}
+
 	var outputFile string
 	var fileName string
 
+	var shipments []shipmentSummary
+	if includeShipments {
+		shipments = summariseShipments(orderData, volumetricDivisor)
+	}
+
 	if outputMode == "overlay" {
 		// Create PDF overlay with proper overlaying
 		outputFile = filepath.Join(outputDir, timestamp+"_overlaid.pdf")
 		fileName = timestamp + "_overlaid.pdf"
-		err = createProperPDFOverlay(pdfPath, orderData, outputFile)
+		if overlayOpts.SplitBy != splitNone {
+			fileName = timestamp + "_split_by_" + overlayOpts.SplitBy + ".zip"
+			outputFile = filepath.Join(outputDir, fileName)
+		}
+		err = createProperPDFOverlay(pdfPath, orderData, outputFile, password, overlayOpts)
+	} else if outputMode == "cutlist" {
+		// Create cut list aggregated by thickness and dimension
+		var ext string
+		ext, err = writeCutList(buildCutList(orderData), cutListFormat, filepath.Join(outputDir, timestamp+"_cutlist"))
+		fileName = timestamp + "_cutlist" + ext
+		outputFile = filepath.Join(outputDir, fileName)
+	} else if outputMode == "picklist" {
+		// Create warehouse pick list grouped by SKU
+		outputFile = filepath.Join(outputDir, timestamp+"_picklist.pdf")
+		fileName = timestamp + "_picklist.pdf"
+		err = writePickListPDF(buildPickList(orderData), outputFile)
+	} else if outputMode == "nesting" {
+		// Create sheet layout diagrams from the cut list
+		outputFile = filepath.Join(outputDir, timestamp+"_nesting.pdf")
+		fileName = timestamp + "_nesting.pdf"
+		err = writeNestingPDF(planNesting(buildCutList(orderData), stock), outputFile)
+	} else if outputMode == "xlsx" {
+		// Create formatted XLSX
+		outputFile = filepath.Join(outputDir, timestamp+"_result.xlsx")
+		fileName = timestamp + "_result.xlsx"
+		var extra []xlsxTable
+		if includeShipments {
+			extra = append(extra, shipmentsTable(shipments))
+		}
+		err = writePDFToXLSX(orderData, outputFile, extra)
 	} else {
 		// Create CSV
 		outputFile = filepath.Join(outputDir, timestamp+"_result.csv")
 		fileName = timestamp + "_result.csv"
 		err = writePDFToCSV(orderData, outputFile)
+
+		if err == nil && includeShipments {
+			shipmentsName := timestamp + "_shipments.csv"
+			err = writeShipmentsCSV(shipments, filepath.Join(outputDir, shipmentsName))
+			downloads = append(downloads, DownloadLink{Label: "Shipment weights", URL: "/outputs/" + shipmentsName})
+		}
 	}
 
 	// Clean up uploaded files
@@ -133,11 +421,28 @@ func ProcessPDFHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Keep the extracted orders for pack-station verification
+	runID := newRunID(timestamp)
+	if err := packRuns.Create(runID, orderData); err != nil {
+		fmt.Printf("Failed to save pack run %s: %v\n", runID, err)
+		runID = ""
+	}
+
 	// Return success response
-	writeJSONSuccess(w, "PDF processed successfully!", "/outputs/"+fileName, fileName)
+	writeJSONResult(w, ProcessResult{
+		Success:   true,
+		Message:   "PDF processed successfully!",
+		OutputURL: "/outputs/" + fileName,
+		FileName:  fileName,
+		Downloads: downloads,
+		RunID:     runID,
+
+		Diagnostics: diagnostics,
+		Unmatched:   unmatchedOrderSKUs(orderData, catalog),
+	})
 }
 
-func extractTextFromPDF(pdfPath string) (string, error) {
+func extractTextFromPDF(pdfPath, password string) (string, error) {
 	// Check if pdftotext is available
 	if !isPdftotextAvailable() {
 		return "", fmt.Errorf("pdftotext is not installed. Please install poppler-utils")
@@ -148,7 +453,8 @@ func extractTextFromPDF(pdfPath string) (string, error) {
 	defer os.Remove(tempFile)
 
 	// Run pdftotext command
-	cmd := exec.Command("pdftotext", pdfPath, tempFile)
+	args := append(popplerPasswordArgs(password), pdfPath, tempFile)
+	cmd := exec.Command("pdftotext", args...)
 	err := cmd.Run()
 	if err != nil {
 		return "", fmt.Errorf("pdftotext command failed: %v", err)
@@ -163,6 +469,48 @@ func extractTextFromPDF(pdfPath string) (string, error) {
 	return string(content), nil
 }
 
+// extractPDFPages splits the pdftotext output into pages and runs the OCR
+// engine on any page without a text layer
+func extractPDFPages(pdfPath, password string, ocr ocrEngine) ([]pdfPage, error) {
+	text, err := extractTextFromPDF(pdfPath, password)
+	if err != nil {
+		return nil, err
+	}
+	return splitPDFPages(text, pdfPath, password, ocr), nil
+}
+
+// splitPDFPages splits pdftotext output into pages, recognising the pages
+// without text with the OCR engine
+func splitPDFPages(text, pdfPath, password string, ocr ocrEngine) []pdfPage {
+	// pdftotext terminates every page with a form feed, so the element after
+	// the last one is not a real page
+	texts := strings.Split(text, "\f")
+	realPages := len(texts)
+	if strings.HasSuffix(text, "\f") {
+		realPages--
+	}
+
+	pages := make([]pdfPage, len(texts))
+	for i, pageText := range texts {
+		pages[i] = pdfPage{Number: i + 1, Text: pageText}
+
+		if ocr == nil || i >= realPages || strings.TrimSpace(pageText) != "" {
+			continue
+		}
+
+		result, err := ocr.RecognizePage(pdfPath, password, i+1)
+		if err != nil {
+			fmt.Printf("OCR failed on page %d: %v\n", i+1, err)
+			continue
+		}
+		pages[i].Text = result.Text
+		pages[i].OCR = true
+		pages[i].OCRConfidence = result.Confidence
+	}
+
+	return pages
+}
+
 func isPdftotextAvailable() bool {
 	_, err := exec.LookPath("pdftotext")
 	return err == nil
@@ -177,26 +525,67 @@ func isPdftkAvailable() bool {
 	return err == nil
 }
 
-func processPDFText(text string, skuMap map[string]PDFSKUMapping) ([]PDFOrderData, error) {
-	// Split by pages
-	pages := strings.Split(text, "\f")
-	if len(pages) == 1 {
-		pages = splitByOrderPattern(text)
+// processPDFPages reads the order lines from the extracted pages. The
+// diagnostics are returned even when no lines are found.
+func processPDFPages(pages []pdfPage, catalog *pdfCatalog) ([]PDFOrderData, *ExtractionDiagnostics, error) {
+	diag := &ExtractionDiagnostics{}
+
+	// Without page breaks, split on order headers instead
+	if len(pages) == 1 && !pages[0].OCR {
+		text := pages[0].Text
+		pages = nil
+		for i, pageText := range splitByOrderPattern(text) {
+			pages = append(pages, pdfPage{Number: i + 1, Text: pageText})
+		}
+		if len(pages) > 1 {
+			diag.Fallbacks = append(diag.Fallbacks, fallbackSplitByOrderPattern)
+			diag.Warnings = append(diag.Warnings, fmt.Sprintf(
+				"the text has no page breaks; split into %d pages at each \"Order Number:\"", len(pages)))
+		}
 	}
 
 	var allOrders []PDFOrderData
+	var current orderContext
 
-	for pageIdx, pageText := range pages {
-		pageNum := pageIdx + 1
-		orders := processPageText(pageText, skuMap, pageNum)
+	for i, page := range pages {
+		pages[i].Type = classifyPage(page.Text)
+		before := current
+
+		// Labels repeat the order but not its items
+		var orders []PDFOrderData
+		if pages[i].Type != pageTypeLabel {
+			orders = processPageText(page.Text, catalog, page.Number, &current)
+		}
+		for j := range orders {
+			orders[j].OCR = page.OCR
+			orders[j].OCRConfidence = page.OCRConfidence
+		}
 		allOrders = append(allOrders, orders...)
+
+		// pdftotext's final form feed leaves an empty element after the last page
+		if i > 0 && i == len(pages)-1 && strings.TrimSpace(page.Text) == "" {
+			continue
+		}
+		diag.Pages = append(diag.Pages, diagnosePage(pages[i], catalog, orders, before))
 	}
 
 	if len(allOrders) == 0 {
-		return processTextSimple(text, skuMap)
+		diag.Fallbacks = append(diag.Fallbacks, fallbackProcessTextSimple)
+		diag.Warnings = append(diag.Warnings,
+			"no page had an order number and SKU; paired the whole text's order numbers and SKUs in order")
+
+		orders, err := processTextSimple(pages, catalog)
+		diag.FallbackPairs = diagnosticPairs(orders)
+		diag.Lines = len(orders)
+		if err != nil {
+			diag.Warnings = append(diag.Warnings, err.Error())
+		}
+		return orders, diag, err
 	}
 
-	return allOrders, nil
+	linkLabelPages(allOrders, pages)
+	diag.Lines = len(allOrders)
+	return allOrders, diag, nil
 }
 
 func splitByOrderPattern(text string) []string {
@@ -219,74 +608,106 @@ func splitByOrderPattern(text string) []string {
 	return pages
 }
 
-func processPageText(pageText string, skuMap map[string]PDFSKUMapping, pageNum int) []PDFOrderData {
-	orderNumberRegex := regexp.MustCompile(`Order Number:\s*(\d{3}-\d{7}-\d{7})`)
-	skuRegex := regexp.MustCompile(`MRC-MR-(\d{4})`)
+var orderNumberRegex = regexp.MustCompile(`Order Number:\s*(\d{3}-\d{7}-\d{7})`)
 
-	orderMatches := orderNumberRegex.FindAllStringSubmatch(pageText, -1)
-	skuMatches := skuRegex.FindAllStringSubmatch(pageText, -1)
+// orderContext is the order whose line items are being read. It carries
+// over page breaks, as continuation pages don't repeat the order header.
+type orderContext struct {
+	OrderNumber string
+	OrderDate   string
+}
 
-	var orders []PDFOrderData
-	minLen := min(len(orderMatches), len(skuMatches))
+// processPageText attributes each SKU on the page to the order header
+// printed before it. SKUs above the page's first header continue the order
+// in current, carried over from an earlier page; only when there is none do
+// they belong to that first header. current is left holding the page's last
+// order.
+func processPageText(pageText string, catalog *pdfCatalog, pageNum int, current *orderContext) []PDFOrderData {
+	orderMatches := orderNumberRegex.FindAllStringSubmatchIndex(pageText, -1)
+	dateMatches := orderDateRegex.FindAllStringSubmatch(pageText, -1)
+
+	enterOrder := func(i int) {
+		current.OrderNumber = pageText[orderMatches[i][2]:orderMatches[i][3]]
+		current.OrderDate = ""
+		if len(dateMatches) > 0 {
+			current.OrderDate = dateMatches[min(i, len(dateMatches)-1)][1]
+		}
+	}
 
-	for i := 0; i < minLen; i++ {
-		if len(orderMatches[i]) > 1 && len(skuMatches[i]) > 1 {
-			orderNumber := orderMatches[i][1]
-			skuID := "MRC-MR-" + skuMatches[i][1]
-
-			var thickness, dimension string
-			if mapping, found := skuMap[skuID]; found {
-				thickness = mapping.Thickness
-				dimension = mapping.Dimension
-			} else {
-				thickness = "N/A"
-				dimension = "N/A"
-			}
+	var orders []PDFOrderData
+	next := 0
+	if current.OrderNumber == "" && len(orderMatches) > 0 {
+		enterOrder(0)
+		next = 1
+	}
+	for _, hit := range findSKUHits(pageText, catalog.matcher) {
+		for ; next < len(orderMatches) && orderMatches[next][0] < hit.start; next++ {
+			enterOrder(next)
+		}
+		if current.OrderNumber == "" {
+			continue // no order seen yet
+		}
 
-			orders = append(orders, PDFOrderData{
-				OrderNumber: orderNumber,
-				SKUID:       skuID,
-				Thickness:   thickness,
-				Dimension:   dimension,
-				PageNumber:  pageNum,
-			})
+		order := PDFOrderData{
+			OrderNumber: current.OrderNumber,
+			OrderDate:   current.OrderDate,
+			SKUID:       hit.id,
+			PageNumber:  pageNum,
+			Quantity:    1,
 		}
+		catalog.resolve(&order)
+
+		orders = append(orders, catalog.expandKit(order)...)
+	}
+
+	// Headers after the page's last SKU start orders that continue overleaf
+	if next < len(orderMatches) {
+		enterOrder(len(orderMatches) - 1)
 	}
 
 	return orders
 }
 
-func processTextSimple(text string, skuMap map[string]PDFSKUMapping) ([]PDFOrderData, error) {
-	orderNumberRegex := regexp.MustCompile(`Order Number:\s*(\d{3}-\d{7}-\d{7})`)
-	skuRegex := regexp.MustCompile(`MRC-MR-(\d{4})`)
+// processTextSimple pairs the document's order numbers and SKUs in order
+// of appearance, ignoring page breaks. Each line takes the page, and the OCR
+// details, of the page its SKU is printed on.
+func processTextSimple(pages []pdfPage, catalog *pdfCatalog) ([]PDFOrderData, error) {
+	texts := make([]string, len(pages))
+	starts := make([]int, len(pages))
+	offset := 0
+	for i, page := range pages {
+		texts[i] = page.Text
+		starts[i] = offset
+		offset += len(page.Text) + 1 // form feed
+	}
+	text := strings.Join(texts, "\f")
 
 	orderMatches := orderNumberRegex.FindAllStringSubmatch(text, -1)
-	skuMatches := skuRegex.FindAllStringSubmatch(text, -1)
+	dateMatches := orderDateRegex.FindAllStringSubmatch(text, -1)
+	skuHits := findSKUHits(text, catalog.matcher)
 
 	var orders []PDFOrderData
-	minLen := min(len(orderMatches), len(skuMatches))
+	minLen := min(len(orderMatches), len(skuHits))
 
 	for i := 0; i < minLen; i++ {
-		if len(orderMatches[i]) > 1 && len(skuMatches[i]) > 1 {
-			orderNumber := orderMatches[i][1]
-			skuID := "MRC-MR-" + skuMatches[i][1]
-
-			var thickness, dimension string
-			if mapping, found := skuMap[skuID]; found {
-				thickness = mapping.Thickness
-				dimension = mapping.Dimension
-			} else {
-				thickness = "N/A"
-				dimension = "N/A"
+		if len(orderMatches[i]) > 1 {
+			hit := skuHits[i]
+			page := pages[sort.Search(len(starts), func(p int) bool { return starts[p] > hit.start })-1]
+
+			order := PDFOrderData{
+				OrderNumber:   orderMatches[i][1],
+				SKUID:         hit.id,
+				PageNumber:    page.Number,
+				OCR:           page.OCR,
+				OCRConfidence: page.OCRConfidence,
+				Quantity:      1,
+			}
+			if len(dateMatches) > 0 {
+				order.OrderDate = dateMatches[min(i, len(dateMatches)-1)][1]
 			}
+			catalog.resolve(&order)
 
-			orders = append(orders, PDFOrderData{
-				OrderNumber: orderNumber,
-				SKUID:       skuID,
-				Thickness:   thickness,
-				Dimension:   dimension,
-				PageNumber:  i + 1,
-			})
+			orders = append(orders, catalog.expandKit(order)...)
 		}
 	}
 
@@ -297,6 +718,92 @@ func processTextSimple(text string, skuMap map[string]PDFSKUMapping) ([]PDFOrder
 	return orders, nil
 }
 
+// findSKUIdentifiers returns, in reading order, the catalog-style SKUs and
+// known marketplace aliases printed in text. An alias printed right next to
+// the SKU it maps to (e.g. "B07XYZ1234 (MRC-MR-0530)") counts once.
+func findSKUIdentifiers(text string, matcher *skuMatcher) []string {
+	var ids []string
+	for _, h := range findSKUHits(text, matcher) {
+		ids = append(ids, h.id)
+	}
+	return ids
+}
+
+// skuHit is an identifier found in text and where it starts
+type skuHit struct {
+	start, end int
+	id         string
+	alias      bool
+}
+
+// findSKUHits is findSKUIdentifiers with the position of each identifier
+func findSKUHits(text string, matcher *skuMatcher) []skuHit {
+	skuRegex := regexp.MustCompile(`MRC-MR-\d{4}`)
+
+	var hits []skuHit
+	for _, loc := range skuRegex.FindAllStringIndex(text, -1) {
+		hits = append(hits, skuHit{loc[0], loc[1], text[loc[0]:loc[1]], false})
+	}
+	if matcher.aliasPattern != nil {
+		for _, loc := range matcher.aliasPattern.FindAllStringIndex(text, -1) {
+			hits = append(hits, skuHit{loc[0], loc[1], text[loc[0]:loc[1]], true})
+		}
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+
+	sort.Slice(hits, func(i, j int) bool { return hits[i].start < hits[j].start })
+
+	const adjacentGap = 150
+	found := []skuHit{hits[0]}
+	prev := hits[0]
+	for _, h := range hits[1:] {
+		if h.start < prev.end {
+			continue // overlapping match
+		}
+		if (h.alias || prev.alias) && h.alias != prev.alias && h.start-prev.end <= adjacentGap &&
+			matcher.Match(h.id).SKU == matcher.Match(prev.id).SKU {
+			prev = h
+			continue
+		}
+		found = append(found, h)
+		prev = h
+	}
+
+	return found
+}
+
+// resolve fills in the catalog fields of order, falling back to aliases and
+// normalised matches when the SKU is not in the catalog verbatim
+func (c *pdfCatalog) resolve(order *PDFOrderData) {
+	match := c.matcher.Match(order.SKUID)
+	order.MatchType = match.Type
+	order.SuggestedSKU = match.SuggestedSKU()
+
+	if _, isKit := c.Kits[match.SKU]; isKit && matchFound(match.Type) {
+		// Components carry the catalog fields; see expandKit
+		order.CatalogSKU = match.SKU
+		return
+	}
+
+	if mapping, found := c.SKUs[match.SKU]; found && matchFound(match.Type) {
+		order.CatalogSKU = match.SKU
+		order.Thickness = mapping.Thickness
+		order.Dimension = mapping.Dimension
+		order.ParsedThickness = mapping.ParsedThickness
+		order.ParsedDimension = mapping.ParsedDimension
+		order.Weight = mapping.Weight
+		order.WeightDerived = mapping.WeightDerived
+		order.PackedSize = mapping.PackedSize
+		order.BinLocation = mapping.BinLocation
+		order.Custom = mapping.Custom
+	} else {
+		order.Thickness = "N/A"
+		order.Dimension = "N/A"
+	}
+}
+
 func loadPDFSKUMapping(filename string) (map[string]PDFSKUMapping, error) {
 	file, err := excelize.OpenFile(filename)
 	if err != nil {
@@ -318,6 +825,15 @@ func loadPDFSKUMapping(filename string) (map[string]PDFSKUMapping, error) {
 		return nil, fmt.Errorf("Excel file must have at least 2 rows (header + data)")
 	}
 
+	// Optional per-thickness densities for rows without a weight
+	densities, err := loadCatalogDensities(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Columns after the sixth are kept by header for overlay templates
+	header := rows[0]
+
 	skuMap := make(map[string]PDFSKUMapping)
 
 	for i := 1; i < len(rows); i++ {
@@ -331,18 +847,79 @@ func loadPDFSKUMapping(filename string) (map[string]PDFSKUMapping, error) {
 		dimension := strings.TrimSpace(row[2])
 
 		if sku != "" {
+			parsedThickness, parsedDimension, err := parseCatalogMeasurements(thickness, dimension)
+			if err != nil {
+				return nil, fmt.Errorf("row %d (%s): %v", i+1, sku, err)
+			}
+
+			// Weight is an optional fourth column
+			weightCell := ""
+			if len(row) > 3 {
+				weightCell = row[3]
+			}
+			weight, weightDerived, err := catalogWeight(weightCell, parsedThickness, parsedDimension, densities)
+			if err != nil {
+				return nil, fmt.Errorf("row %d (%s): %v", i+1, sku, err)
+			}
+
+			// Packed dimensions are an optional fifth column
+			var packedSize PackedSize
+			if len(row) > 4 && strings.TrimSpace(row[4]) != "" {
+				packedSize, err = parsePackedSize(row[4])
+				if err != nil {
+					return nil, fmt.Errorf("row %d (%s): %v", i+1, sku, err)
+				}
+			}
+
+			// Bin location is an optional sixth column
+			binLocation := ""
+			if len(row) > 5 {
+				binLocation = strings.TrimSpace(row[5])
+			}
+
+			var custom map[string]string
+			for col := 6; col < len(row) && col < len(header); col++ {
+				name := strings.TrimSpace(header[col])
+				if name == "" {
+					continue
+				}
+				if custom == nil {
+					custom = make(map[string]string)
+				}
+				custom[name] = strings.TrimSpace(row[col])
+			}
+
 			skuMap[sku] = PDFSKUMapping{
-				SKU:       sku,
-				Thickness: thickness,
-				Dimension: dimension,
+				SKU:             sku,
+				Thickness:       thickness,
+				Dimension:       dimension,
+				Weight:          weight,
+				WeightDerived:   weightDerived,
+				PackedSize:      packedSize,
+				BinLocation:     binLocation,
+				Custom:          custom,
+				ParsedThickness: parsedThickness,
+				ParsedDimension: parsedDimension,
 			}
 		}
 	}
 
+	// Rows added from the results screen fill in SKUs the upload lacks
+	err = mergeLearnedSKUs(skuMap, densities, func(m PDFSKUMapping) PDFSKUMapping { return m })
+	if err != nil {
+		return nil, err
+	}
+
 	return skuMap, nil
 }
 
 func writePDFToCSV(orders []PDFOrderData, filename string) error {
+	return writePDFCSVWithColumns(orders, filename, nil, nil)
+}
+
+// writePDFCSVWithColumns writes the standard PDF CSV with extra columns
+// appended; extraValues returns the extra cells for line i
+func writePDFCSVWithColumns(orders []PDFOrderData, filename string, extraHeaders []string, extraValues func(i int, order PDFOrderData) []string) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("failed to create CSV file: %v", err)
@@ -353,20 +930,33 @@ func writePDFToCSV(orders []PDFOrderData, filename string) error {
 	defer writer.Flush()
 
 	// Write header
-	err = writer.Write([]string{"Order Number", "SKU ID", "Thickness", "Dimension", "Page Number"})
+	header := []string{"Order Number", "SKU ID", "Thickness", "Dimension", "Page Number", "Text Source", "OCR Confidence", "Match Type", "Suggested SKU", "Kit SKU", "Quantity", "Line Weight (kg)", "Weight Source"}
+	err = writer.Write(append(header, extraHeaders...))
 	if err != nil {
 		return fmt.Errorf("failed to write CSV header: %v", err)
 	}
 
 	// Write data
-	for _, order := range orders {
-		err = writer.Write([]string{
+	for i, order := range orders {
+		row := []string{
 			order.OrderNumber,
 			order.SKUID,
 			order.Thickness,
 			order.Dimension,
 			fmt.Sprintf("%d", order.PageNumber),
-		})
+			order.textSource(),
+			order.ocrConfidenceString(),
+			order.MatchType,
+			order.SuggestedSKU,
+			order.KitSKU,
+			fmt.Sprintf("%d", order.Quantity),
+			fmt.Sprintf("%.3f", order.lineWeight()),
+			order.weightSource(),
+		}
+		if extraValues != nil {
+			row = append(row, extraValues(i, order)...)
+		}
+		err = writer.Write(row)
 		if err != nil {
 			return fmt.Errorf("failed to write CSV row: %v", err)
 		}
@@ -375,19 +965,74 @@ func writePDFToCSV(orders []PDFOrderData, filename string) error {
 	return nil
 }
 
+func writePDFToXLSX(orders []PDFOrderData, filename string, extra []xlsxTable) error {
+	report := xlsxReport{
+		Extra:     extra,
+		Headers:   []string{"Order Number", "SKU ID", "Thickness", "Dimension", "Page Number", "Status", "Text Source", "OCR Confidence", "Match Type", "Suggested SKU", "Kit SKU", "Quantity", "Line Weight (kg)", "Weight Source"},
+		StatusCol: 5,
+	}
+
+	foundCount := 0
+	ocrLines := 0
+	uniqueOrders := make(map[string]bool)
+	for _, order := range orders {
+		status := "Found"
+		if order.Thickness == "N/A" && order.Dimension == "N/A" {
+			status = "Not Found"
+		} else {
+			foundCount++
+		}
+		uniqueOrders[order.OrderNumber] = true
+
+		var ocrConfidence interface{}
+		if order.OCR {
+			ocrConfidence = order.OCRConfidence
+			ocrLines++
+		}
+
+		report.Rows = append(report.Rows, []interface{}{
+			order.OrderNumber,
+			order.SKUID,
+			order.Thickness,
+			order.Dimension,
+			order.PageNumber,
+			status,
+			order.textSource(),
+			ocrConfidence,
+			order.MatchType,
+			order.SuggestedSKU,
+			order.KitSKU,
+			order.Quantity,
+			order.lineWeight(),
+			order.weightSource(),
+		})
+	}
+
+	report.Summary = []xlsxSummaryItem{
+		{"Total Lines", len(orders)},
+		{"Unique Orders", len(uniqueOrders)},
+		{"Found", foundCount},
+		{"Not Found", len(orders) - foundCount},
+		{"Match Rate (%)", matchRate(foundCount, len(orders))},
+		{"OCR Lines", ocrLines},
+	}
+
+	return writeXLSXReport(report, filename)
+}
+
 // NEW: Proper PDF overlay implementation based on your working code
-func createProperPDFOverlay(inputPDF string, orders []PDFOrderData, outputPDF string) error {
+func createProperPDFOverlay(inputPDF string, orders []PDFOrderData, outputPDF, password string, opts overlayOptions) error {
 	// Check if pdftk is available for proper overlay
 	if !isPdftkAvailable() {
+		if opts.SplitBy != splitNone {
+			return fmt.Errorf("splitting the PDF needs pdftk")
+		}
 		// Fallback to simple overlay if pdftk is not available
-		return createSimplePDFOverlay(orders, outputPDF)
+		return createSimplePDFOverlay(orders, outputPDF, opts)
 	}
 
-	// Group orders by page
-	pageOrders := make(map[int][]PDFOrderData)
-	for _, order := range orders {
-		pageOrders[order.PageNumber] = append(pageOrders[order.PageNumber], order)
-	}
+	// Group orders by the page they are stamped on
+	pageOrders := stampPageOrders(orders, opts.StampOn)
 
 	// Create temporary directory for overlay files
 	tempDir := filepath.Join(os.TempDir(), fmt.Sprintf("temp_overlays_%d", time.Now().Unix()))
@@ -395,25 +1040,29 @@ func createProperPDFOverlay(inputPDF string, orders []PDFOrderData, outputPDF st
 	defer os.RemoveAll(tempDir) // Clean up temp directory
 
 	// Get total number of pages in the input PDF
-	totalPages, err := getPDFPageCount(inputPDF)
+	totalPages, err := getPDFPageCount(inputPDF, password)
 	if err != nil {
 		return fmt.Errorf("failed to get page count: %v", err)
 	}
 
+	// Overlay pages match each source page's size and rotation
+	geometry := getPDFPageGeometry(inputPDF, password, totalPages)
+
 	// Create overlay for each page
 	overlayFiles := make([]string, 0)
 	for pageNum := 1; pageNum <= totalPages; pageNum++ {
 		overlayFile := filepath.Join(tempDir, fmt.Sprintf("overlay_page_%d.pdf", pageNum))
+		page := pageGeometryFor(geometry, pageNum)
 
 		if pageOrderList, hasOrders := pageOrders[pageNum]; hasOrders {
 			// Create overlay with annotation text
-			err = createTransparentOverlay(overlayFile, pageOrderList)
+			err = createTransparentOverlay(overlayFile, pageOrderList, opts, page)
 			if err != nil {
 				return fmt.Errorf("failed to create overlay for page %d: %v", pageNum, err)
 			}
 		} else {
 			// Create empty overlay (transparent page)
-			err = createEmptyOverlay(overlayFile)
+			err = createEmptyOverlay(overlayFile, page)
 			if err != nil {
 				return fmt.Errorf("failed to create empty overlay for page %d: %v", pageNum, err)
 			}
@@ -428,18 +1077,65 @@ func createProperPDFOverlay(inputPDF string, orders []PDFOrderData, outputPDF st
 		return fmt.Errorf("failed to combine overlay PDFs: %v", err)
 	}
 
+	// Summary of problem pages to put in front of the stamped pages
+	var coverPDF string
+	if opts.HighlightProblems {
+		if problems := findProblemPages(orders); len(problems) > 0 {
+			coverPDF = filepath.Join(tempDir, "00_problems.pdf")
+			if err := writeProblemCover(problems, coverPDF); err != nil {
+				return fmt.Errorf("failed to create problem summary: %v", err)
+			}
+		}
+	}
+
 	// Use pdftk to overlay the annotations on the original PDF
-	err = overlayWithPdftk(inputPDF, multiOverlayPDF, outputPDF)
+	stampedPDF := outputPDF
+	if opts.PageSort != pageSortNone || opts.SplitBy != splitNone || coverPDF != "" {
+		stampedPDF = filepath.Join(tempDir, "stamped.pdf")
+	}
+	err = overlayWithPdftk(inputPDF, multiOverlayPDF, stampedPDF, password)
 	if err != nil {
 		return fmt.Errorf("failed to overlay PDFs: %v", err)
 	}
 
+	// Split into one file per order or thickness; outputPDF is then a ZIP
+	if opts.SplitBy != splitNone {
+		var extra []string
+		if coverPDF != "" {
+			extra = append(extra, coverPDF)
+		}
+		if err := splitPDFToZip(stampedPDF, orders, totalPages, opts.SplitBy, outputPDF, extra...); err != nil {
+			return fmt.Errorf("failed to split PDF: %v", err)
+		}
+		return nil
+	}
+
+	// Regroup the stamped pages for batch packing
+	if opts.PageSort != pageSortNone {
+		sortedPDF := outputPDF
+		if coverPDF != "" {
+			sortedPDF = filepath.Join(tempDir, "sorted.pdf")
+		}
+		groups := groupPages(orders, totalPages, opts.PageSort)
+		if err := reorderPDFPages(stampedPDF, groups, opts.Separators, sortedPDF); err != nil {
+			return fmt.Errorf("failed to reorder pages: %v", err)
+		}
+		stampedPDF = sortedPDF
+	}
+
+	if coverPDF != "" {
+		if err := combineOverlayPDFs([]string{coverPDF, stampedPDF}, outputPDF); err != nil {
+			return fmt.Errorf("failed to add problem summary: %v", err)
+		}
+	}
+
 	return nil
 }
 
-func getPDFPageCount(pdfPath string) (int, error) {
+func getPDFPageCount(pdfPath, password string) (int, error) {
 	// Use pdfinfo to get page count (part of poppler-utils)
-	cmd := exec.Command("pdfinfo", pdfPath)
+	args := append(popplerPasswordArgs(password), pdfPath)
+	cmd := exec.Command("pdfinfo", args...)
 	output, err := cmd.Output()
 	if err != nil {
 		// Fallback: assume 30 pages if pdfinfo is not available
@@ -460,48 +1156,104 @@ func getPDFPageCount(pdfPath string) (int, error) {
 	return 30, nil
 }
 
-func createTransparentOverlay(filename string, orders []PDFOrderData) error {
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.AddPage()
-	pdf.SetFont("Arial", "B", 14) // Increased font size
-	pdf.SetTextColor(0, 0, 0)     // Black color
+func createTransparentOverlay(filename string, orders []PDFOrderData, opts overlayOptions, page pageGeometry) error {
+	text := opts.Text
+	style := text.Style
+	layout := newOverlayLayout(page, style.Anchor)
+	fontSize := layout.FontSize(style.FontSize)
 
-	// Position annotations in a corner or bottom of the page to avoid content overlap
-	startX := 10.0
-	startY := 245.0 // Bottom of page
+	pdf, fonts := newPDFDocumentWithFont("P", "A4", style.FontFamily)
+	tr := fonts.Encode
+	addOverlayPage(pdf, page)
+
+	// Frame pages that need checking and say why
+	if opts.HighlightProblems {
+		if problems := findProblemPages(orders); len(problems) > 0 {
+			drawProblemMarks(pdf, fonts, layout, problems[0], style.Anchor == anchorTopLeft || style.Anchor == anchorTopRight)
+		}
+	}
 
-	// Add a semi-transparent background for better readability
-	pdf.SetFillColor(255, 255, 255) // White background
-	pdf.SetAlpha(0.9, "Normal")     // Semi-transparent
+	fonts.SetFont(pdf, "B", fontSize)
 
-	for i, order := range orders {
-		y := startY + float64(i)*15.0 // Spacing between annotations
+	// Annotations fill a band at the bottom or top of the page to avoid the
+	// invoice content, overflowing into further columns
+	rightAligned := style.Anchor == anchorBottomRight || style.Anchor == anchorTopRight
 
-		// Ensure we don't go off the page
-		if y > 280 {
-			startX += 120.0 // Move to next column
-			startY = 245.0
-			y = startY
+	// 8 mm cells at 14 pt; QR codes are taller than a line of text
+	cellHeight := fontSize*0.3528 + 3
+	rowSpacing := math.Max(15*layout.Scale, cellHeight+7*layout.Scale)
+	if opts.QRCode {
+		// QR codes keep their module size to stay scannable, so rows never
+		// get closer than the largest code
+		qrSide, err := maxQRCodeSize(orders)
+		if err != nil {
+			return err
 		}
+		rowSpacing = math.Max(math.Max(20*layout.Scale, cellHeight+12*layout.Scale), qrSide+layout.Scale)
+	}
+	rowsPerColumn := int(layout.BandHeight/rowSpacing) + 1
 
-		// Create annotation text
-		text := fmt.Sprintf("Thickness: %s | Dimension: %s", order.Thickness, order.Dimension)
+	for i, order := range orders {
+		column := float64(i / rowsPerColumn)
+		y := layout.BandTop + float64(i%rowsPerColumn)*rowSpacing
+
+		label, err := text.render(order, false)
+		if err != nil {
+			return err
+		}
+		label = tr(label)
+		textWidth := fonts.StringWidth(pdf, label)
+
+		// Scannable codes sit towards the right edge, with the text to
+		// their left when right-aligned
+		var x float64
+		if rightAligned {
+			right := layout.Right() - column*layout.ColumnWidth
+			codesLeft, err := drawOverlayCodes(pdf, orders, i, opts, y, right, 0)
+			if err != nil {
+				return err
+			}
+			x = right - textWidth
+			if codesLeft < right {
+				x = codesLeft - 2 - textWidth
+			}
+		} else {
+			x = layout.Margin + column*layout.ColumnWidth
+			if _, err := drawOverlayCodes(pdf, orders, i, opts, y, math.Max(layout.Right(), x+textWidth+4), x+textWidth+4); err != nil {
+				return err
+			}
+		}
 
-		// Add background rectangle for better readability
-		textWidth := pdf.GetStringWidth(text)
-		pdf.Rect(startX-2, y-2, textWidth+4, 10, "F")
+		// Background rectangle for readability; unmatched SKUs are printed
+		// in red on pink
+		problem := opts.HighlightProblems && lineProblem(order) != ""
+		if problem {
+			pdf.SetFillColor(255, 225, 225)
+			pdf.SetTextColor(problemRed[0], problemRed[1], problemRed[2])
+		} else {
+			pdf.SetFillColor(255, 255, 255)
+			pdf.SetTextColor(text.r, text.g, text.b)
+		}
+		opacity := style.BackgroundOpacity
+		if problem {
+			opacity = math.Max(opacity, 0.9)
+		}
+		if opacity > 0 {
+			pdf.SetAlpha(opacity, "Normal")
+			pdf.Rect(x-2, y-2, textWidth+4, cellHeight+2, "F")
+			pdf.SetAlpha(1, "Normal")
+		}
 
-		// Add text
-		pdf.SetXY(startX, y)
-		pdf.Cell(textWidth, 8, text)
+		pdf.SetXY(x, y)
+		fonts.CellFormat(pdf, textWidth, cellHeight, label, "", 0, "L", false, 0, "")
 	}
 
 	return pdf.OutputFileAndClose(filename)
 }
 
-func createEmptyOverlay(filename string) error {
+func createEmptyOverlay(filename string, page pageGeometry) error {
 	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.AddPage()
+	addOverlayPage(pdf, page)
 	// Create a completely transparent/empty page
 	return pdf.OutputFileAndClose(filename)
 }
@@ -513,20 +1265,24 @@ func combineOverlayPDFs(overlayFiles []string, outputFile string) error {
 	return cmd.Run()
 }
 
-func overlayWithPdftk(inputPDF, overlayPDF, outputPDF string) error {
+func overlayWithPdftk(inputPDF, overlayPDF, outputPDF, password string) error {
+	input := append([]string{inputPDF}, pdftkPasswordArgs(password)...)
+
 	// Use pdftk to overlay the multi-page overlay onto the original PDF
-	cmd := exec.Command("pdftk", inputPDF, "multistamp", overlayPDF, "output", outputPDF)
+	cmd := exec.Command("pdftk", append(input, "multistamp", overlayPDF, "output", outputPDF)...)
 	err := cmd.Run()
 	if err != nil {
 		// Try alternative pdftk command
-		cmd = exec.Command("pdftk", inputPDF, "stamp", overlayPDF, "output", outputPDF)
+		cmd = exec.Command("pdftk", append(input, "stamp", overlayPDF, "output", outputPDF)...)
 		err = cmd.Run()
 	}
 	return err
 }
 
 // Fallback simple overlay for when pdftk is not available
-func createSimplePDFOverlay(orders []PDFOrderData, outputPDF string) error {
+func createSimplePDFOverlay(orders []PDFOrderData, outputPDF string, opts overlayOptions) error {
+	text := opts.Text
+
 	// Group orders by page
 	pageOrders := make(map[int][]PDFOrderData)
 	for _, order := range orders {
@@ -534,24 +1290,38 @@ func createSimplePDFOverlay(orders []PDFOrderData, outputPDF string) error {
 	}
 
 	// Create a simple overlay PDF (this is the original implementation)
-	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf, fonts := newPDFDocumentWithFont("P", "A4", text.Style.FontFamily)
+	tr := fonts.Encode
+	cellHeight := text.Style.FontSize*0.3528 + 3
+
+	if opts.HighlightProblems {
+		if problems := findProblemPages(orders); len(problems) > 0 {
+			addProblemCoverPage(pdf, fonts, problems)
+		}
+	}
 
 	for pageNum, pageOrderList := range pageOrders {
 		pdf.AddPage()
-		pdf.SetFont("Arial", "B", 12)
-		pdf.SetTextColor(0, 0, 0)
+		fonts.SetFont(pdf, "B", text.Style.FontSize)
+		pdf.SetTextColor(text.r, text.g, text.b)
 
-		y := 20.0
-		pdf.SetXY(10, y)
+		pdf.SetXY(10, 20)
 		pdf.Cell(0, 10, fmt.Sprintf("Page %d Annotations:", pageNum))
 
-		y += 15
+		pdf.SetY(35)
 		for _, order := range pageOrderList {
-			pdf.SetXY(10, y)
-			text := fmt.Sprintf("Order: %s | SKU: %s | Thickness: %s | Dimension: %s",
-				order.OrderNumber, order.SKUID, order.Thickness, order.Dimension)
-			pdf.Cell(0, 8, text)
-			y += 10
+			label, err := text.render(order, true)
+			if err != nil {
+				return err
+			}
+			if opts.HighlightProblems && lineProblem(order) != "" {
+				pdf.SetTextColor(problemRed[0], problemRed[1], problemRed[2])
+			} else {
+				pdf.SetTextColor(text.r, text.g, text.b)
+			}
+			pdf.SetX(10)
+			fonts.MultiCell(pdf, 0, cellHeight, tr(label), "", "L", false)
+			pdf.Ln(2)
 		}
 	}
 
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLeadingSKUsContinueTheCarriedOrder(t *testing.T) {
+	pages := []pdfPage{
+		{Number: 1, Text: "Tax Invoice\nOrder Number: 111-1111111-1111111\nMRC-MR-0530"},
+		{Number: 2, Text: "MRC-MR-0700\nTax Invoice\nOrder Number: 222-2222222-2222222\nMRC-MR-0531\nOrder Number: 333-3333333-3333333\nMRC-MR-0532"},
+	}
+
+	orders, diag, err := processPDFPages(pages, testCatalog("MRC-MR-0530", "MRC-MR-0531", "MRC-MR-0532", "MRC-MR-0700"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"MRC-MR-0530": "111-1111111-1111111",
+		"MRC-MR-0700": "111-1111111-1111111",
+		"MRC-MR-0531": "222-2222222-2222222",
+		"MRC-MR-0532": "333-3333333-3333333",
+	}
+	if len(orders) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(orders), len(want))
+	}
+	for _, o := range orders {
+		if o.OrderNumber != want[o.SKUID] {
+			t.Errorf("%s attributed to %s, want %s", o.SKUID, o.OrderNumber, want[o.SKUID])
+		}
+		if o.SKUID == "MRC-MR-0700" && o.PageNumber != 2 {
+			t.Errorf("%s on page %d, want 2", o.SKUID, o.PageNumber)
+		}
+	}
+
+	var warned bool
+	for _, w := range diag.Pages[1].Warnings {
+		warned = warned || strings.Contains(w, "above the first order number, attributed to 111-1111111-1111111")
+	}
+	if !warned {
+		t.Errorf("page 2 warnings = %v, want one about the SKU above its header", diag.Pages[1].Warnings)
+	}
+}
+
+func TestLeadingSKUsWithoutCarriedOrderBelongToFirstHeader(t *testing.T) {
+	pages := []pdfPage{
+		{Number: 1, Text: "MRC-MR-0700\nTax Invoice\nOrder Number: 111-1111111-1111111\nMRC-MR-0530"},
+	}
+
+	orders, _, err := processPDFPages(pages, testCatalog("MRC-MR-0530", "MRC-MR-0700"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("got %d lines, want 2", len(orders))
+	}
+	for _, o := range orders {
+		if o.OrderNumber != "111-1111111-1111111" {
+			t.Errorf("%s attributed to %s, want 111-1111111-1111111", o.SKUID, o.OrderNumber)
+		}
+	}
+}
+
+func TestContinuationPageKeepsPreviousOrder(t *testing.T) {
+	pages := []pdfPage{
+		{Number: 1, Text: "Tax Invoice\nOrder Number: 111-1111111-1111111\nMRC-MR-0530"},
+		{Number: 2, Text: "MRC-MR-0531"},
+	}
+
+	orders, _, err := processPDFPages(pages, testCatalog("MRC-MR-0530", "MRC-MR-0531"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("got %d lines, want 2", len(orders))
+	}
+	if o := orders[1]; o.OrderNumber != "111-1111111-1111111" || o.PageNumber != 2 {
+		t.Errorf("continued line = order %s page %d, want 111-1111111-1111111 page 2", o.OrderNumber, o.PageNumber)
+	}
+}
@@ -0,0 +1,182 @@
+// handlers/pdf_split.go
+package handlers
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Ways to split the stamped invoice PDF into separate files
+const (
+	splitNone      = "none"
+	splitOrder     = "order"
+	splitThickness = "thickness"
+)
+
+// requestSplitBy validates the splitBy form field
+func requestSplitBy(value string) string {
+	switch value {
+	case splitOrder, splitThickness:
+		return value
+	}
+	return splitNone
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9.+-]+`)
+
+// splitFileName is the name of a group's file inside the ZIP. Orders are
+// named by order number; thickness groups are numbered in sort order, e.g.
+// "01_1.5mm.pdf", and a page mixing thicknesses gets a combined group such
+// as "03_2mm_+_5mm.pdf". Pages without orders go to "unassigned.pdf".
+func splitFileName(g pageGroup, index int, splitBy string) string {
+	if len(g.Orders) == 0 {
+		return "unassigned.pdf"
+	}
+	name := strings.Trim(unsafeFileNameChars.ReplaceAllString(g.Label, "_"), "_")
+	if splitBy == splitOrder {
+		return name + ".pdf"
+	}
+	return fmt.Sprintf("%02d_%s.pdf", index+1, name)
+}
+
+// splitPDFToZip extracts each group's pages of inputPDF into its own PDF and
+// writes them all to zipPath, after any extra files
+func splitPDFToZip(inputPDF string, orders []PDFOrderData, totalPages int, splitBy, zipPath string, extra ...string) error {
+	groups := groupPages(orders, totalPages, pageSortThickness)
+	if splitBy == splitOrder {
+		groups = orderPageGroups(orders, totalPages)
+	}
+
+	tempDir, err := os.MkdirTemp("", "split_")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := append([]string{}, extra...)
+	for i, g := range groups {
+		file := filepath.Join(tempDir, splitFileName(g, i, splitBy))
+		if err := extractPDFPagesTo(inputPDF, g.Pages, file); err != nil {
+			return fmt.Errorf("failed to extract %s: %v", filepath.Base(file), err)
+		}
+		files = append(files, file)
+	}
+
+	return zipFiles(files, zipPath)
+}
+
+// orderPageGroups makes one group per order holding every page its lines
+// or shipping label are on, in order of each order's first page. A page
+// listing several orders is copied into each of their groups; pages without
+// orders form a final "No orders" group.
+func orderPageGroups(orders []PDFOrderData, totalPages int) []pageGroup {
+	var groups []pageGroup
+	index := make(map[string]int)
+	assigned := make(map[int]bool)
+
+	addPage := func(orderNumber string, page int) {
+		if page < 1 || page > totalPages {
+			return
+		}
+		i, exists := index[orderNumber]
+		if !exists {
+			i = len(groups)
+			index[orderNumber] = i
+			groups = append(groups, pageGroup{Label: orderNumber, Orders: []string{orderNumber}})
+		}
+		g := &groups[i]
+		for _, p := range g.Pages {
+			if p == page {
+				return
+			}
+		}
+		g.Pages = append(g.Pages, page)
+		assigned[page] = true
+	}
+	for _, order := range orders {
+		addPage(order.OrderNumber, order.PageNumber)
+		if order.LabelPage != 0 {
+			addPage(order.OrderNumber, order.LabelPage)
+		}
+	}
+
+	for i := range groups {
+		sort.Ints(groups[i].Pages)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Pages[0] < groups[j].Pages[0]
+	})
+
+	var empty []int
+	for page := 1; page <= totalPages; page++ {
+		if !assigned[page] {
+			empty = append(empty, page)
+		}
+	}
+	if len(empty) > 0 {
+		groups = append(groups, pageGroup{Label: "No orders", Pages: empty})
+	}
+
+	return groups
+}
+
+// extractPDFPagesTo writes the given pages of inputPDF, in order, to outputPDF
+func extractPDFPagesTo(inputPDF string, pages []int, outputPDF string) error {
+	args := []string{inputPDF, "cat"}
+	for _, page := range pages {
+		args = append(args, fmt.Sprintf("%d", page))
+	}
+	args = append(args, "output", outputPDF)
+
+	cmd := exec.Command("pdftk", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pdftk cat failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// zipFiles stores files at the top level of a new ZIP archive
+func zipFiles(files []string, zipPath string) error {
+	out, err := os.Create(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to create ZIP file: %v", err)
+	}
+	defer out.Close()
+
+	archive := zip.NewWriter(out)
+	for _, file := range files {
+		if err := addFileToZip(archive, file); err != nil {
+			archive.Close()
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write ZIP file: %v", err)
+	}
+	return nil
+}
+
+func addFileToZip(archive *zip.Writer, file string) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", filepath.Base(file), err)
+	}
+	defer in.Close()
+
+	w, err := archive.Create(filepath.Base(file))
+	if err != nil {
+		return fmt.Errorf("failed to add %s to ZIP: %v", filepath.Base(file), err)
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		return fmt.Errorf("failed to add %s to ZIP: %v", filepath.Base(file), err)
+	}
+	return nil
+}
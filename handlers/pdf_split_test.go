@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitByOrderCopiesSharedPages(t *testing.T) {
+	orders := []PDFOrderData{
+		{OrderNumber: "111-1111111-1111111", PageNumber: 1},
+		{OrderNumber: "111-1111111-1111111", PageNumber: 2},
+		{OrderNumber: "222-2222222-2222222", PageNumber: 2},
+		{OrderNumber: "333-3333333-3333333", PageNumber: 4, LabelPage: 3},
+	}
+
+	groups := orderPageGroups(orders, 5)
+
+	want := []struct {
+		file  string
+		pages []int
+	}{
+		{"111-1111111-1111111.pdf", []int{1, 2}},
+		{"222-2222222-2222222.pdf", []int{2}},
+		{"333-3333333-3333333.pdf", []int{3, 4}},
+		{"unassigned.pdf", []int{5}},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("got %d groups, want %d: %+v", len(groups), len(want), groups)
+	}
+	for i, w := range want {
+		file := splitFileName(groups[i], i, splitOrder)
+		if file != w.file || !reflect.DeepEqual(groups[i].Pages, w.pages) {
+			t.Errorf("group %d = %s pages %v, want %s pages %v", i, file, groups[i].Pages, w.file, w.pages)
+		}
+	}
+}
+
+func TestSplitByThicknessCombinesMixedPages(t *testing.T) {
+	thin := PDFOrderData{OrderNumber: "111-1111111-1111111", PageNumber: 1, Thickness: "2mm", ParsedThickness: Thickness{MM: 2}}
+	thick := PDFOrderData{OrderNumber: "111-1111111-1111111", PageNumber: 1, Thickness: "5mm", ParsedThickness: Thickness{MM: 5}}
+	other := PDFOrderData{OrderNumber: "222-2222222-2222222", PageNumber: 2, Thickness: "5mm", ParsedThickness: Thickness{MM: 5}}
+
+	groups := groupPages([]PDFOrderData{thin, thick, other}, 2, pageSortThickness)
+
+	var files []string
+	for i, g := range groups {
+		files = append(files, splitFileName(g, i, splitThickness))
+	}
+	if want := []string{"01_2mm_+_5mm.pdf", "02_5mm.pdf"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+}
@@ -0,0 +1,171 @@
+// handlers/pick_list.go
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pickListItem is one SKU to pick with everything the picker needs
+type pickListItem struct {
+	SKU         string
+	Thickness   string
+	Dimension   string
+	BinLocation string
+	Quantity    int
+	Orders      []string
+	Found       bool
+}
+
+// buildPickList totals the physical items to pick per SKU. Kits are already
+// expanded into their components, so pickers see what is on the shelf.
+// Items are sorted by bin location so the list follows the warehouse, with
+// unlocated and unmatched SKUs last.
+func buildPickList(orders []PDFOrderData) []pickListItem {
+	var items []pickListItem
+	index := make(map[string]int)
+
+	for _, order := range orders {
+		sku := order.CatalogSKU
+		found := sku != ""
+		if !found {
+			sku = order.SKUID
+		}
+
+		i, exists := index[sku]
+		if !exists {
+			i = len(items)
+			index[sku] = i
+			items = append(items, pickListItem{
+				SKU:         sku,
+				Thickness:   order.Thickness,
+				Dimension:   order.Dimension,
+				BinLocation: order.BinLocation,
+				Found:       found,
+			})
+		}
+		item := &items[i]
+
+		quantity := order.Quantity
+		if quantity < 1 {
+			quantity = 1
+		}
+		item.Quantity += quantity
+
+		if n := len(item.Orders); n == 0 || item.Orders[n-1] != order.OrderNumber {
+			item.Orders = append(item.Orders, order.OrderNumber)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Found != b.Found {
+			return a.Found
+		}
+		if (a.BinLocation == "") != (b.BinLocation == "") {
+			return a.BinLocation != ""
+		}
+		if a.BinLocation != b.BinLocation {
+			return a.BinLocation < b.BinLocation
+		}
+		return a.SKU < b.SKU
+	})
+
+	return items
+}
+
+// writePickListPDF renders the pick list as an A4 table with a tick box per
+// SKU and the orders that need it
+func writePickListPDF(items []pickListItem, filename string) error {
+	pdf, fonts := newPDFDocument("P", "A4")
+	pdf.SetMargins(10, 12, 10)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		fonts.SetFont(pdf, "", 8)
+		fonts.CellFormat(pdf, 0, 6, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	tr := fonts.Encode
+
+	totalQuantity := 0
+	orderSet := make(map[string]bool)
+	for _, item := range items {
+		totalQuantity += item.Quantity
+		for _, order := range item.Orders {
+			orderSet[order] = true
+		}
+	}
+
+	pdf.AddPage()
+	fonts.SetFont(pdf, "B", 16)
+	fonts.CellFormat(pdf, 0, 10, "Pick List", "", 1, "L", false, 0, "")
+	fonts.SetFont(pdf, "", 9)
+	fonts.CellFormat(pdf, 0, 6, fmt.Sprintf("Generated %s - %d SKUs, %d items, %d orders",
+		time.Now().Format("02 Jan 2006 15:04"), len(items), totalQuantity, len(orderSet)), "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	headers := []string{"", "SKU", "Thickness", "Dimension", "Qty", "Bin", "Orders"}
+	widths := []float64{8, 30, 20, 34, 12, 20, 66}
+	const lineHeight = 5.0
+
+	writeHeader := func() {
+		fonts.SetFont(pdf, "B", 10)
+		pdf.SetFillColor(217, 225, 242)
+		for i, h := range headers {
+			fonts.CellFormat(pdf, widths[i], 8, h, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		fonts.SetFont(pdf, "", 9)
+	}
+	writeHeader()
+
+	for _, item := range items {
+		orderLines := pdf.SplitLines([]byte(strings.Join(item.Orders, ", ")), widths[6]-2)
+		rowHeight := float64(len(orderLines))*lineHeight + 2
+		if rowHeight < 8 {
+			rowHeight = 8
+		}
+
+		if pdf.GetY()+rowHeight > 282 {
+			pdf.AddPage()
+			writeHeader()
+		}
+
+		x, y := pdf.GetXY()
+
+		// Tick box
+		pdf.Rect(x, y, widths[0], rowHeight, "D")
+		pdf.Rect(x+2, y+rowHeight/2-2, 4, 4, "D")
+		x += widths[0]
+
+		thickness, dimension := item.Thickness, item.Dimension
+		if !item.Found {
+			thickness, dimension = "Not found", ""
+		}
+		cells := []string{item.SKU, thickness, dimension, fmt.Sprintf("%d", item.Quantity), item.BinLocation}
+		aligns := []string{"L", "L", "L", "C", "C"}
+		for i, text := range cells {
+			pdf.SetXY(x, y)
+			if i == 3 {
+				fonts.SetFont(pdf, "B", 11)
+			}
+			fonts.CellFormat(pdf, widths[i+1], rowHeight, tr(text), "1", 0, aligns[i], false, 0, "")
+			if i == 3 {
+				fonts.SetFont(pdf, "", 9)
+			}
+			x += widths[i+1]
+		}
+
+		pdf.Rect(x, y, widths[6], rowHeight, "D")
+		for i, line := range orderLines {
+			pdf.SetXY(x+1, y+1+float64(i)*lineHeight)
+			fonts.CellFormat(pdf, widths[6]-2, lineHeight, string(line), "", 0, "L", false, 0, "")
+		}
+
+		pdf.SetXY(10, y+rowHeight)
+	}
+
+	return pdf.OutputFileAndClose(filename)
+}
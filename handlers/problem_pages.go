@@ -0,0 +1,190 @@
+// handlers/problem_pages.go
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// problemRed is the colour of problem banners, borders and text
+var problemRed = [3]int{200, 0, 0}
+
+// lineProblem describes why a line's thickness and dimension can't be
+// trusted, or is empty when the line is fine
+func lineProblem(order PDFOrderData) string {
+	switch order.MatchType {
+	case matchNone:
+		return fmt.Sprintf("SKU %s not in catalog", order.SKUID)
+	case matchFuzzy:
+		return fmt.Sprintf("SKU %s not in catalog, closest is %s", order.SKUID, order.SuggestedSKU)
+	}
+	return ""
+}
+
+// pageProblem is a source page that needs checking before it is packed
+type pageProblem struct {
+	Page   int
+	Orders []string
+	Issues []string
+}
+
+// findProblemPages lists the pages with unmatched SKUs, or with
+// more than one SKU, in page order
+func findProblemPages(orders []PDFOrderData) []pageProblem {
+	pageOrders := make(map[int][]PDFOrderData)
+	var pages []int
+	for _, order := range orders {
+		if _, seen := pageOrders[order.PageNumber]; !seen {
+			pages = append(pages, order.PageNumber)
+		}
+		pageOrders[order.PageNumber] = append(pageOrders[order.PageNumber], order)
+	}
+	sort.Ints(pages)
+
+	var problems []pageProblem
+	for _, page := range pages {
+		p := pageProblem{Page: page}
+		skuSet := make(map[string]bool)
+		var skus []string
+		for _, line := range pageOrders[page] {
+			if n := len(p.Orders); n == 0 || p.Orders[n-1] != line.OrderNumber {
+				p.Orders = append(p.Orders, line.OrderNumber)
+			}
+
+			// Kit components all come from one invoice SKU
+			sku := line.SKUID
+			if line.KitSKU != "" {
+				sku = line.KitSKU
+			}
+			if !skuSet[sku] {
+				skuSet[sku] = true
+				skus = append(skus, sku)
+			}
+
+			if issue := lineProblem(line); issue != "" {
+				p.Issues = append(p.Issues, issue)
+			}
+		}
+		if len(skus) > 1 {
+			p.Issues = append(p.Issues, fmt.Sprintf("%d SKUs on one page: %s", len(skus), strings.Join(skus, ", ")))
+		}
+
+		if len(p.Issues) > 0 {
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// drawProblemMarks frames a problem page in red and prints a banner with
+// its issues along the top, or along the bottom when the annotations are at
+// the top
+func drawProblemMarks(pdf *gofpdf.Fpdf, fonts *pdfFonts, layout overlayLayout, problem pageProblem, bannerAtBottom bool) {
+	pdf.SetDrawColor(problemRed[0], problemRed[1], problemRed[2])
+	pdf.SetLineWidth(1.5)
+	pdf.Rect(1, 1, layout.Width-2, layout.Height-2, "D")
+
+	fontSize := layout.FontSize(11)
+	fonts.SetFont(pdf, "B", fontSize)
+	text := fonts.Encode("CHECK BEFORE PACKING: " + strings.Join(problem.Issues, "; "))
+	lines := fonts.SplitLines(pdf, text, layout.Width-4-2*layout.Margin)
+	if len(lines) > 3 {
+		lines = lines[:3]
+	}
+	lineHeight := fontSize*0.3528 + 1.5
+	bannerHeight := float64(len(lines))*lineHeight + 3
+
+	y := 2.5
+	if bannerAtBottom {
+		y = layout.Height - 2.5 - bannerHeight
+	}
+	pdf.SetFillColor(problemRed[0], problemRed[1], problemRed[2])
+	pdf.Rect(layout.Margin, y, layout.Width-2*layout.Margin, bannerHeight, "F")
+	pdf.SetTextColor(255, 255, 255)
+	for i, line := range lines {
+		pdf.SetXY(layout.Margin+2, y+1.5+float64(i)*lineHeight)
+		fonts.CellFormat(pdf, layout.Width-4-2*layout.Margin, lineHeight, line, "", 0, "L", false, 0, "")
+	}
+}
+
+// addProblemCoverPage adds an A4 page listing every problem page so they can
+// be checked before packing starts
+func addProblemCoverPage(pdf *gofpdf.Fpdf, fonts *pdfFonts, problems []pageProblem) {
+	tr := fonts.Encode
+	pdf.AddPageFormat("P", gofpdf.SizeType{Wd: 210, Ht: 297})
+	pdf.SetMargins(10, 12, 10)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(0.2)
+
+	pdf.SetXY(10, 12)
+	fonts.SetFont(pdf, "B", 16)
+	pdf.SetTextColor(problemRed[0], problemRed[1], problemRed[2])
+	fonts.CellFormat(pdf, 0, 10, fmt.Sprintf("%d pages to check before packing", len(problems)), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	fonts.SetFont(pdf, "", 9)
+	fonts.CellFormat(pdf, 0, 6, "Generated "+time.Now().Format("02 Jan 2006 15:04")+
+		" - pages are numbered as in the original invoice PDF and marked in red", "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	headers := []string{"Page", "Orders", "Problem"}
+	widths := []float64{15, 55, 120}
+	const lineHeight = 5.0
+
+	writeHeader := func() {
+		fonts.SetFont(pdf, "B", 10)
+		pdf.SetFillColor(217, 225, 242)
+		for i, h := range headers {
+			fonts.CellFormat(pdf, widths[i], 8, h, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		fonts.SetFont(pdf, "", 9)
+	}
+	writeHeader()
+
+	for _, p := range problems {
+		orderLines := fonts.SplitLines(pdf, tr(strings.Join(p.Orders, ", ")), widths[1]-2)
+		var issueLines []string
+		for _, issue := range p.Issues {
+			issueLines = append(issueLines, fonts.SplitLines(pdf, tr(issue), widths[2]-2)...)
+		}
+		rows := len(issueLines)
+		if len(orderLines) > rows {
+			rows = len(orderLines)
+		}
+		rowHeight := float64(rows)*lineHeight + 2
+
+		if pdf.GetY()+rowHeight > 270 {
+			pdf.AddPageFormat("P", gofpdf.SizeType{Wd: 210, Ht: 297})
+			pdf.SetXY(10, 12)
+			writeHeader()
+		}
+
+		x, y := pdf.GetXY()
+		fonts.CellFormat(pdf, widths[0], rowHeight, fmt.Sprintf("%d", p.Page), "1", 0, "C", false, 0, "")
+		for col, lines := range [][]string{orderLines, issueLines} {
+			cellX := x + widths[0]
+			if col == 1 {
+				cellX += widths[1]
+			}
+			pdf.Rect(cellX, y, widths[col+1], rowHeight, "D")
+			for i, line := range lines {
+				pdf.SetXY(cellX+1, y+1+float64(i)*lineHeight)
+				fonts.CellFormat(pdf, widths[col+1]-2, lineHeight, line, "", 0, "L", false, 0, "")
+			}
+		}
+		pdf.SetXY(10, y+rowHeight)
+	}
+}
+
+// writeProblemCover writes the problem summary as its own PDF
+func writeProblemCover(problems []pageProblem, filename string) error {
+	pdf, fonts := newPDFDocument("P", "A4")
+	pdf.SetAutoPageBreak(false, 0)
+	addProblemCoverPage(pdf, fonts, problems)
+	return pdf.OutputFileAndClose(filename)
+}
@@ -0,0 +1,192 @@
+// handlers/shipments.go
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+)
+
+// DefaultVolumetricDivisor is the courier divisor in cm³ per kg used when a
+// request does not supply one. It is set from the -volumetric-divisor flag.
+var DefaultVolumetricDivisor = 5000.0
+
+// shipmentSummary is the courier booking view of one order
+type shipmentSummary struct {
+	OrderNumber      string
+	Lines            int
+	Pieces           int
+	ActualWeight     float64 // kg
+	Packed           PackedSize
+	VolumetricWeight float64 // kg
+	Chargeable       string  // "Actual" or "Volumetric"
+	ChargeableWeight float64 // kg
+	Incomplete       bool    // some line lacked a weight or size
+}
+
+// summariseShipments groups lines by order number, in order of first
+// appearance. Actual weight is catalog weight × quantity. Lines are packed
+// by stacking: the box takes the longest length and width of any line and
+// the sum of line heights. A line without packed dimensions is treated as a
+// flat stack of mats of its catalog size and thickness.
+func summariseShipments(orders []PDFOrderData, divisor float64) []shipmentSummary {
+	var summaries []shipmentSummary
+	index := make(map[string]int)
+
+	for _, order := range orders {
+		i, exists := index[order.OrderNumber]
+		if !exists {
+			i = len(summaries)
+			index[order.OrderNumber] = i
+			summaries = append(summaries, shipmentSummary{OrderNumber: order.OrderNumber})
+		}
+		s := &summaries[i]
+
+		quantity := order.Quantity
+		if quantity < 1 {
+			quantity = 1
+		}
+
+		s.Lines++
+		s.Pieces += quantity
+		s.ActualWeight += order.lineWeight()
+		if order.Weight == 0 {
+			s.Incomplete = true
+		}
+
+		box, ok := linePackedSize(order, quantity)
+		if !ok {
+			s.Incomplete = true
+			continue
+		}
+		s.Packed.LengthCM = math.Max(s.Packed.LengthCM, box.LengthCM)
+		s.Packed.WidthCM = math.Max(s.Packed.WidthCM, box.WidthCM)
+		s.Packed.HeightCM += box.HeightCM
+	}
+
+	for i := range summaries {
+		s := &summaries[i]
+		if divisor > 0 {
+			s.VolumetricWeight = s.Packed.VolumeCM3() / divisor
+		}
+
+		if s.VolumetricWeight > s.ActualWeight {
+			s.Chargeable = "Volumetric"
+			s.ChargeableWeight = s.VolumetricWeight
+		} else {
+			s.Chargeable = "Actual"
+			s.ChargeableWeight = s.ActualWeight
+		}
+	}
+
+	return summaries
+}
+
+// linePackedSize returns the box needed for quantity pieces of the line
+func linePackedSize(order PDFOrderData, quantity int) (PackedSize, bool) {
+	if !order.PackedSize.IsZero() {
+		box := order.PackedSize
+		box.HeightCM *= float64(quantity)
+		return box, true
+	}
+
+	if order.ParsedDimension.IsZero() || order.ParsedThickness.MM <= 0 {
+		return PackedSize{}, false
+	}
+
+	w, h := order.ParsedDimension.In("cm")
+	return PackedSize{
+		LengthCM: math.Max(w, h),
+		WidthCM:  math.Min(w, h),
+		HeightCM: order.ParsedThickness.MM / 10 * float64(quantity),
+	}, true
+}
+
+var shipmentHeaders = []string{
+	"Order Number", "Lines", "Pieces", "Actual Weight (kg)",
+	"Packed L (cm)", "Packed W (cm)", "Packed H (cm)",
+	"Volumetric Weight (kg)", "Chargeable", "Chargeable Weight (kg)", "Incomplete",
+}
+
+func writeShipmentsCSV(summaries []shipmentSummary, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err := writer.Write(shipmentHeaders); err != nil {
+		return fmt.Errorf("failed to write CSV header: %v", err)
+	}
+
+	for _, s := range summaries {
+		err := writer.Write([]string{
+			s.OrderNumber,
+			strconv.Itoa(s.Lines),
+			strconv.Itoa(s.Pieces),
+			fmt.Sprintf("%.3f", s.ActualWeight),
+			fmt.Sprintf("%.1f", s.Packed.LengthCM),
+			fmt.Sprintf("%.1f", s.Packed.WidthCM),
+			fmt.Sprintf("%.1f", s.Packed.HeightCM),
+			fmt.Sprintf("%.3f", s.VolumetricWeight),
+			s.Chargeable,
+			fmt.Sprintf("%.3f", s.ChargeableWeight),
+			yesNo(s.Incomplete),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write CSV row: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// shipmentsTable is the Shipments sheet of the XLSX report
+func shipmentsTable(summaries []shipmentSummary) xlsxTable {
+	table := xlsxTable{Name: "Shipments", Headers: shipmentHeaders, StatusCol: -1}
+	for _, s := range summaries {
+		table.Rows = append(table.Rows, []interface{}{
+			s.OrderNumber,
+			s.Lines,
+			s.Pieces,
+			roundTo3(s.ActualWeight),
+			roundTo1(s.Packed.LengthCM),
+			roundTo1(s.Packed.WidthCM),
+			roundTo1(s.Packed.HeightCM),
+			roundTo3(s.VolumetricWeight),
+			s.Chargeable,
+			roundTo3(s.ChargeableWeight),
+			yesNo(s.Incomplete),
+		})
+	}
+	return table
+}
+
+func roundTo1(v float64) float64 { return math.Round(v*10) / 10 }
+func roundTo2(v float64) float64 { return math.Round(v*100) / 100 }
+func roundTo3(v float64) float64 { return math.Round(v*1000) / 1000 }
+
+func yesNo(b bool) string {
+	if b {
+		return "Yes"
+	}
+	return "No"
+}
+
+// requestVolumetricDivisor parses the volumetricDivisor form field, falling
+// back to the server-wide default
+func requestVolumetricDivisor(value string) (float64, error) {
+	if value == "" {
+		return DefaultVolumetricDivisor, nil
+	}
+	divisor, err := strconv.ParseFloat(value, 64)
+	if err != nil || divisor <= 0 {
+		return 0, fmt.Errorf("invalid volumetric divisor %q", value)
+	}
+	return divisor, nil
+}
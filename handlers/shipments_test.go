@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummariseShipments(t *testing.T) {
+	mat, _ := parseDimension("24x36 in")
+	orders := []PDFOrderData{
+		// 3 x 2 kg in 60x40x10 boxes plus a 50x50x5 box: stacked 60x50x35
+		{OrderNumber: "111", Weight: 2, Quantity: 3, PackedSize: PackedSize{60, 40, 10}},
+		{OrderNumber: "222", Weight: 10, Quantity: 1, PackedSize: PackedSize{30, 20, 10}},
+		{OrderNumber: "111", Weight: 1, Quantity: 1, PackedSize: PackedSize{50, 50, 5}},
+		// No weight; packed as a stack of two 5mm mats, 91.44x60.96x1
+		{OrderNumber: "333", Quantity: 2, ParsedThickness: Thickness{MM: 5}, ParsedDimension: mat},
+		// No packed size or dimension
+		{OrderNumber: "444", Weight: 1, Quantity: 1},
+	}
+
+	want := []shipmentSummary{
+		{OrderNumber: "111", Lines: 2, Pieces: 4, ActualWeight: 7, Packed: PackedSize{60, 50, 35},
+			VolumetricWeight: 21, Chargeable: "Volumetric", ChargeableWeight: 21},
+		{OrderNumber: "222", Lines: 1, Pieces: 1, ActualWeight: 10, Packed: PackedSize{30, 20, 10},
+			VolumetricWeight: 1.2, Chargeable: "Actual", ChargeableWeight: 10},
+		{OrderNumber: "333", Lines: 1, Pieces: 2, Packed: PackedSize{91.44, 60.96, 1},
+			VolumetricWeight: 91.44 * 60.96 / 5000, Chargeable: "Volumetric", ChargeableWeight: 91.44 * 60.96 / 5000, Incomplete: true},
+		{OrderNumber: "444", Lines: 1, Pieces: 1, ActualWeight: 1, Chargeable: "Actual", ChargeableWeight: 1, Incomplete: true},
+	}
+
+	got := summariseShipments(orders, 5000)
+	if len(got) != len(want) {
+		t.Fatalf("got %d shipments, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if !sameShipment(got[i], w) {
+			t.Errorf("shipment %d = %+v, want %+v", i, got[i], w)
+		}
+	}
+
+	// A larger divisor lowers the volumetric weight: 60x40x30 cm / 6000
+	if s := summariseShipments(orders[:1], 6000)[0]; !closeTo(s.VolumetricWeight, 12) {
+		t.Errorf("volumetric weight at 6000 = %v, want 12", s.VolumetricWeight)
+	}
+	if s := summariseShipments(orders, 6000)[0]; !closeTo(s.ChargeableWeight, 17.5) || s.Chargeable != "Volumetric" {
+		t.Errorf("chargeable at 6000 = %s %v, want Volumetric 17.5", s.Chargeable, s.ChargeableWeight)
+	}
+}
+
+func sameShipment(a, b shipmentSummary) bool {
+	return a.OrderNumber == b.OrderNumber && a.Lines == b.Lines && a.Pieces == b.Pieces &&
+		closeTo(a.ActualWeight, b.ActualWeight) &&
+		closeTo(a.Packed.LengthCM, b.Packed.LengthCM) && closeTo(a.Packed.WidthCM, b.Packed.WidthCM) &&
+		closeTo(a.Packed.HeightCM, b.Packed.HeightCM) &&
+		closeTo(a.VolumetricWeight, b.VolumetricWeight) &&
+		a.Chargeable == b.Chargeable && closeTo(a.ChargeableWeight, b.ChargeableWeight) &&
+		a.Incomplete == b.Incomplete
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
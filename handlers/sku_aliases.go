@@ -0,0 +1,228 @@
+// handlers/sku_aliases.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	aliasStorePath   = "./data/sku_aliases.json"
+	aliasesSheetName = "Aliases"
+)
+
+// SKUAlias maps a marketplace identifier (ASIN, FSN, channel seller SKU)
+// to a catalog SKU
+type SKUAlias struct {
+	Alias     string    `json:"alias"`
+	SKU       string    `json:"sku"`
+	Source    string    `json:"source,omitempty"` // e.g. ASIN, FSN, Seller SKU
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AliasListResult struct {
+	Success bool       `json:"success"`
+	Aliases []SKUAlias `json:"aliases"`
+}
+
+// aliasStore is the server-side alias table, persisted as JSON
+type aliasStore struct {
+	*jsonStore[SKUAlias]
+}
+
+var skuAliases = &aliasStore{newJSONStore(aliasStorePath, "alias store", func(a SKUAlias) string { return a.Alias })}
+
+// Put adds or replaces an alias
+func (s *aliasStore) Put(alias SKUAlias) error {
+	alias.UpdatedAt = time.Now()
+	return s.jsonStore.Put(alias)
+}
+
+// Merged returns the stored aliases overlaid with those from an uploaded
+// catalog, as alias -> catalog SKU. Uploaded aliases win.
+func (s *aliasStore) Merged(uploaded map[string]string) (map[string]string, error) {
+	stored, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]string, len(stored)+len(uploaded))
+	for _, alias := range stored {
+		merged[alias.Alias] = alias.SKU
+	}
+	for alias, sku := range uploaded {
+		merged[alias] = sku
+	}
+	return merged, nil
+}
+
+// loadCatalogAliases reads the optional "Aliases" sheet (Alias, SKU) from an
+// uploaded catalog workbook
+func loadCatalogAliases(filename string) (map[string]string, error) {
+	aliases := make(map[string]string)
+
+	file, err := excelize.OpenFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Excel file: %v", err)
+	}
+	defer file.Close()
+
+	if idx, _ := file.GetSheetIndex(aliasesSheetName); idx < 0 {
+		return aliases, nil
+	}
+
+	rows, err := file.GetRows(aliasesSheetName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s sheet: %v", aliasesSheetName, err)
+	}
+
+	// Skip header row
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) < 2 {
+			continue
+		}
+
+		alias := strings.TrimSpace(row[0])
+		sku := strings.TrimSpace(row[1])
+		if alias != "" && sku != "" {
+			aliases[alias] = sku
+		}
+	}
+
+	return aliases, nil
+}
+
+// loadRequestAliases merges the uploaded catalog's aliases with the stored
+// alias table
+func loadRequestAliases(catalogPath string) (map[string]string, error) {
+	uploaded, err := loadCatalogAliases(catalogPath)
+	if err != nil {
+		return nil, err
+	}
+	return skuAliases.Merged(uploaded)
+}
+
+// aliasTargets returns the SKUs an alias may point at: the learned rows,
+// plus the rows and kits of the catalog workbook uploaded as mapping, if any
+func aliasTargets(r *http.Request) ([]string, error) {
+	skuMap := make(map[string]PDFSKUMapping)
+	var kits map[string][]kitComponent
+
+	mappingFile, mappingHeader, err := r.FormFile("mapping")
+	switch {
+	case err == http.ErrMissingFile || err == http.ErrNotMultipart:
+		err = mergeLearnedSKUs(skuMap, nil, func(m PDFSKUMapping) PDFSKUMapping { return m })
+		if err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to read mapping file: %v", err)
+	default:
+		defer mappingFile.Close()
+		mappingPath := filepath.Join("./uploads", fmt.Sprintf("%d_aliases_%s", time.Now().UnixNano(), filepath.Base(mappingHeader.Filename)))
+		if err := saveFile(mappingFile, mappingPath); err != nil {
+			return nil, fmt.Errorf("failed to save mapping file: %v", err)
+		}
+		defer os.Remove(mappingPath)
+
+		if skuMap, err = loadPDFSKUMapping(mappingPath); err != nil {
+			return nil, err
+		}
+		if kits, err = loadCatalogKits(mappingPath, skuMap); err != nil {
+			return nil, err
+		}
+	}
+
+	targets := make([]string, 0, len(skuMap)+len(kits))
+	for sku := range skuMap {
+		targets = append(targets, sku)
+	}
+	for kit := range kits {
+		if _, exists := skuMap[kit]; !exists {
+			targets = append(targets, kit)
+		}
+	}
+	return targets, nil
+}
+
+// AliasesHandler manages the stored alias table. The target SKU of a new
+// alias must be a learned row or in the catalog uploaded with it, as
+// aliases to unknown SKUs are ignored when matching.
+//
+//	GET    /aliases                                   list aliases
+//	POST   /aliases  alias, sku, source, by, mapping  add or replace an alias
+//	DELETE /aliases?alias=...                         remove an alias
+func AliasesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		aliases, err := skuAliases.List()
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AliasListResult{Success: true, Aliases: aliases})
+
+	case "POST":
+		alias := strings.TrimSpace(r.FormValue("alias"))
+		sku := strings.TrimSpace(r.FormValue("sku"))
+		if alias == "" || sku == "" {
+			writeJSONError(w, "Both alias and sku are required", http.StatusBadRequest)
+			return
+		}
+
+		targets, err := aliasTargets(r)
+		if err != nil {
+			writeJSONError(w, "Failed to load catalog: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		match := newSKUMatcher(targets, nil).Match(sku)
+		if !matchFound(match.Type) {
+			writeJSONError(w, fmt.Sprintf("SKU %s is not in the catalog or the learned rows", sku), http.StatusBadRequest)
+			return
+		}
+		sku = match.SKU // the catalog's spelling
+
+		err = skuAliases.Put(SKUAlias{
+			Alias:     alias,
+			SKU:       sku,
+			Source:    strings.TrimSpace(r.FormValue("source")),
+			UpdatedBy: requestEditor(r),
+		})
+		if err != nil {
+			writeJSONError(w, "Failed to save alias: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONSuccess(w, fmt.Sprintf("Alias %s → %s saved", alias, sku), "", "")
+
+	case "DELETE":
+		alias := strings.TrimSpace(r.URL.Query().Get("alias"))
+		if alias == "" {
+			writeJSONError(w, "alias is required", http.StatusBadRequest)
+			return
+		}
+
+		found, err := skuAliases.Delete(alias)
+		if err != nil {
+			writeJSONError(w, "Failed to delete alias: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			writeJSONError(w, "Alias not found: "+alias, http.StatusNotFound)
+			return
+		}
+		writeJSONSuccess(w, "Alias "+alias+" deleted", "", "")
+
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestAliasTargetMustBeKnown(t *testing.T) {
+	dir := t.TempDir()
+	savedAliases, savedLearned := skuAliases, learnedSKUs
+	defer func() { skuAliases, learnedSKUs = savedAliases, savedLearned }()
+	skuAliases = &aliasStore{newJSONStore(filepath.Join(dir, "aliases.json"), "alias store", func(a SKUAlias) string { return a.Alias })}
+	learnedSKUs = &learnedSKUStore{newJSONStore(filepath.Join(dir, "learned.json"), "learned SKU store", func(l LearnedSKU) string { return l.SKU })}
+
+	if err := learnedSKUs.Put(LearnedSKU{SKU: "MRC-MR-0900", Thickness: "5mm", Dimension: "24x36 in", Weight: "1.2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(alias, sku string) *httptest.ResponseRecorder {
+		form := url.Values{"alias": {alias}, "sku": {sku}}
+		req := httptest.NewRequest("POST", "/aliases", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		AliasesHandler(rec, req)
+		return rec
+	}
+
+	if rec := post("B0UNKNOWN", "MRC-MR-0999"); rec.Code != http.StatusBadRequest {
+		t.Errorf("alias to unknown SKU: status %d, want 400", rec.Code)
+	}
+
+	if rec := post("B0LEARNED", "mrc-mr-900"); rec.Code != http.StatusOK {
+		t.Fatalf("alias to learned SKU: status %d: %s", rec.Code, rec.Body)
+	}
+	aliases, err := skuAliases.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aliases) != 1 || aliases[0].SKU != "MRC-MR-0900" {
+		t.Errorf("stored %+v, want one alias to MRC-MR-0900", aliases)
+	}
+}
+
+func TestAliasTargetFromUploadedCatalog(t *testing.T) {
+	dir := t.TempDir()
+	savedAliases, savedLearned := skuAliases, learnedSKUs
+	defer func() { skuAliases, learnedSKUs = savedAliases, savedLearned }()
+	skuAliases = &aliasStore{newJSONStore(filepath.Join(dir, "aliases.json"), "alias store", func(a SKUAlias) string { return a.Alias })}
+	learnedSKUs = &learnedSKUStore{newJSONStore(filepath.Join(dir, "learned.json"), "learned SKU store", func(l LearnedSKU) string { return l.SKU })}
+
+	catalog := excelize.NewFile()
+	catalog.SetSheetRow("Sheet1", "A1", &[]string{"SKU", "Thickness", "Dimension", "Weight"})
+	catalog.SetSheetRow("Sheet1", "A2", &[]string{"MRC-MR-0530", "5mm", "24x36 in", "1.2"})
+	var workbook bytes.Buffer
+	if err := catalog.Write(&workbook); err != nil {
+		t.Fatal(err)
+	}
+
+	// The workbook is saved under ./uploads while it is read
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("uploads", 0755); err != nil {
+		t.Fatal(err)
+	}
+	post := func(sku string) int {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("alias", "B0CATALOG")
+		form.WriteField("sku", sku)
+		part, _ := form.CreateFormFile("mapping", "catalog.xlsx")
+		part.Write(workbook.Bytes())
+		form.Close()
+
+		req := httptest.NewRequest("POST", "/aliases", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rec := httptest.NewRecorder()
+		AliasesHandler(rec, req)
+		return rec.Code
+	}
+
+	if code := post("MRC-MR-0531"); code != http.StatusBadRequest {
+		t.Errorf("alias to SKU missing from the catalog: status %d, want 400", code)
+	}
+	if code := post("MRC-MR-0530"); code != http.StatusOK {
+		t.Errorf("alias to catalog SKU: status %d, want 200", code)
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -21,13 +20,40 @@ type SKUData struct {
 	Thickness string
 	Dimension string
 	Weight    float64
+
+	WeightDerived   bool // Weight was calculated from area, thickness and density
+	ParsedThickness Thickness
+	ParsedDimension Dimension
+}
+
+// weightSource describes where Weight came from for the report
+func (d SKUData) weightSource() string {
+	switch {
+	case d.WeightDerived:
+		return "Derived"
+	case d.Weight == 0:
+		return "Missing"
+	}
+	return "Catalog"
 }
 
 type ProcessResult struct {
-	Success   bool   `json:"success"`
-	Message   string `json:"message"`
-	OutputURL string `json:"output_url,omitempty"`
-	FileName  string `json:"file_name,omitempty"`
+	Success   bool           `json:"success"`
+	Message   string         `json:"message"`
+	OutputURL string         `json:"output_url,omitempty"`
+	FileName  string         `json:"file_name,omitempty"`
+	Downloads []DownloadLink `json:"downloads,omitempty"`
+	RunID     string         `json:"run_id,omitempty"` // pack-station run for PDF results
+
+	Diagnostics *ExtractionDiagnostics `json:"diagnostics,omitempty"`
+	Preview     *OrderPreview          `json:"preview,omitempty"` // lines to review before output
+	Unmatched   []UnmatchedSKU         `json:"unmatched,omitempty"`
+}
+
+// DownloadLink is an additional output file offered alongside the main one
+type DownloadLink struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
 }
 
 func ProcessSKUHandler(w http.ResponseWriter, r *http.Request) {
@@ -70,11 +96,18 @@ func ProcessSKUHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	outputFormat := r.FormValue("outputFormat")
+	if outputFormat != "csv" && outputFormat != "xlsx" {
+		outputFormat = "csv" // default
+	}
+
 	// Process content
-	outputFile := filepath.Join(outputDir, timestamp+"_sku_report.csv")
-	fileName := timestamp + "_sku_report.csv"
+	fileName := timestamp + "_sku_report." + outputFormat
+	outputFile := filepath.Join(outputDir, fileName)
+
+	dimensionUnit := requestDimensionUnit(r.FormValue("dimensionUnit"))
 
-	err = processSKUContent(textContent, mappingPath, outputFile)
+	unmatched, err := processSKUContent(textContent, mappingPath, outputFile, outputFormat, dimensionUnit)
 
 	// Clean up uploaded file
 	os.Remove(mappingPath)
@@ -85,29 +118,56 @@ func ProcessSKUHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return success response
-	writeJSONSuccess(w, "SKU extraction completed successfully!", "/outputs/"+fileName, fileName)
+	writeJSONResult(w, ProcessResult{
+		Success:   true,
+		Message:   "SKU extraction completed successfully!",
+		OutputURL: "/outputs/" + fileName,
+		FileName:  fileName,
+		Unmatched: unmatched,
+	})
 }
 
-func processSKUContent(textContent, mappingPath, outputPath string) error {
+// processSKUContent writes the SKU report and returns the SKUs that are not
+// in the catalog
+func processSKUContent(textContent, mappingPath, outputPath, outputFormat, dimensionUnit string) ([]UnmatchedSKU, error) {
 	// Extract SKUs from text content
 	skus := extractSKUs(textContent)
 	if len(skus) == 0 {
-		return fmt.Errorf("no SKUs found in text content")
+		return nil, fmt.Errorf("no SKUs found in text content")
 	}
 
 	// Read Excel mapping file
 	skuMap, err := readExcelMapping(mappingPath)
 	if err != nil {
-		return fmt.Errorf("error reading Excel mapping: %v", err)
+		return nil, fmt.Errorf("error reading Excel mapping: %v", err)
+	}
+	for sku, data := range skuMap {
+		data.Dimension = formatDimension(data.Dimension, data.ParsedDimension, dimensionUnit)
+		skuMap[sku] = data
 	}
 
-	// Create output CSV
-	err = createOutputCSV(skus, skuMap, outputPath)
+	// Marketplace aliases from the upload's Aliases sheet and the alias table
+	aliases, err := loadRequestAliases(mappingPath)
 	if err != nil {
-		return fmt.Errorf("error creating output CSV: %v", err)
+		return nil, fmt.Errorf("error reading SKU aliases: %v", err)
 	}
+	unmatched := unmatchedSKUs(skus, newSKUDataMatcher(skuMap, aliases))
 
-	return nil
+	// Create output report
+	if outputFormat == "xlsx" {
+		err = createOutputXLSX(skus, skuMap, aliases, outputPath)
+		if err != nil {
+			return nil, fmt.Errorf("error creating output XLSX: %v", err)
+		}
+		return unmatched, nil
+	}
+
+	err = createOutputCSV(skus, skuMap, aliases, outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("error creating output CSV: %v", err)
+	}
+
+	return unmatched, nil
 }
 
 // extractSKUs extracts all SKU values from the input text
@@ -147,6 +207,12 @@ func readExcelMapping(filename string) (map[string]SKUData, error) {
 		return nil, fmt.Errorf("no sheets found in Excel file")
 	}
 
+	// Optional per-thickness densities for rows without a weight
+	densities, err := loadCatalogDensities(filename)
+	if err != nil {
+		return nil, err
+	}
+
 	sheet := file.Sheets[0]
 	maxRow := sheet.MaxRow
 	maxCol := sheet.MaxCol
@@ -179,30 +245,62 @@ func readExcelMapping(filename string) (map[string]SKUData, error) {
 			dimension = strings.TrimSpace(dimensionCell.String())
 		}
 
-		weight := 0.0
+		weightStr := ""
 		if weightCell, err := sheet.Cell(rowIdx, 3); err == nil {
-			weightStr := strings.TrimSpace(weightCell.String())
-			if weightStr != "" {
-				if parsedWeight, parseErr := strconv.ParseFloat(weightStr, 64); parseErr == nil {
-					weight = parsedWeight
-				}
-			}
+			weightStr = strings.TrimSpace(weightCell.String())
+		}
+
+		parsedThickness, parsedDimension, err := parseCatalogMeasurements(thickness, dimension)
+		if err != nil {
+			return nil, fmt.Errorf("row %d (%s): %v", rowIdx+1, sku, err)
+		}
+
+		weight, weightDerived, err := catalogWeight(weightStr, parsedThickness, parsedDimension, densities)
+		if err != nil {
+			return nil, fmt.Errorf("row %d (%s): %v", rowIdx+1, sku, err)
 		}
 
 		// Store in map
 		skuMap[sku] = SKUData{
-			SKU:       sku,
-			Thickness: thickness,
-			Dimension: dimension,
-			Weight:    weight,
+			SKU:             sku,
+			Thickness:       thickness,
+			Dimension:       dimension,
+			Weight:          weight,
+			WeightDerived:   weightDerived,
+			ParsedThickness: parsedThickness,
+			ParsedDimension: parsedDimension,
 		}
 	}
 
+	// Rows added from the results screen fill in SKUs the upload lacks
+	err = mergeLearnedSKUs(skuMap, densities, func(m PDFSKUMapping) SKUData {
+		return SKUData{
+			SKU:             m.SKU,
+			Thickness:       m.Thickness,
+			Dimension:       m.Dimension,
+			Weight:          m.Weight,
+			WeightDerived:   m.WeightDerived,
+			ParsedThickness: m.ParsedThickness,
+			ParsedDimension: m.ParsedDimension,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return skuMap, nil
 }
 
+func newSKUDataMatcher(skuMap map[string]SKUData, aliases map[string]string) *skuMatcher {
+	skus := make([]string, 0, len(skuMap))
+	for sku := range skuMap {
+		skus = append(skus, sku)
+	}
+	return newSKUMatcher(skus, aliases)
+}
+
 // createOutputCSV creates the output CSV file with mapped data
-func createOutputCSV(skus []string, skuMap map[string]SKUData, filename string) error {
+func createOutputCSV(skus []string, skuMap map[string]SKUData, aliases map[string]string, filename string) error {
 	// Create output file
 	file, err := os.Create(filename)
 	if err != nil {
@@ -215,25 +313,31 @@ func createOutputCSV(skus []string, skuMap map[string]SKUData, filename string)
 	defer writer.Flush()
 
 	// Write header
-	header := []string{"SKU", "Thickness", "Dimension", "Weight (kg)", "Status"}
+	header := []string{"SKU", "Thickness", "Dimension", "Weight (kg)", "Status", "Match Type", "Suggested SKU", "Weight Source"}
 	if err := writer.Write(header); err != nil {
 		return fmt.Errorf("failed to write header: %v", err)
 	}
 
+	matcher := newSKUDataMatcher(skuMap, aliases)
+
 	// Count stats
 	foundCount := 0
 	notFoundCount := 0
 
 	// Write data rows in the same order as input
 	for _, sku := range skus {
-		if data, exists := skuMap[sku]; exists {
+		match := matcher.Match(sku)
+		if data, exists := skuMap[match.SKU]; exists && matchFound(match.Type) {
 			// SKU found in mapping
 			row := []string{
-				data.SKU,
+				sku,
 				data.Thickness,
 				data.Dimension,
 				fmt.Sprintf("%.3f", data.Weight),
 				"Found",
+				match.Type,
+				match.SuggestedSKU(),
+				data.weightSource(),
 			}
 			if err := writer.Write(row); err != nil {
 				return fmt.Errorf("failed to write row: %v", err)
@@ -247,6 +351,9 @@ func createOutputCSV(skus []string, skuMap map[string]SKUData, filename string)
 				"",
 				"",
 				"Not Found",
+				match.Type,
+				match.SuggestedSKU(),
+				"",
 			}
 			if err := writer.Write(row); err != nil {
 				return fmt.Errorf("failed to write row: %v", err)
@@ -262,6 +369,9 @@ func createOutputCSV(skus []string, skuMap map[string]SKUData, filename string)
 		fmt.Sprintf("%d Not Found", notFoundCount),
 		fmt.Sprintf("%.1f%% Match Rate", float64(foundCount)/float64(len(skus))*100),
 		"Summary",
+		"",
+		"",
+		"",
 	}
 	if err := writer.Write(summaryRow); err != nil {
 		return fmt.Errorf("failed to write summary: %v", err)
@@ -270,28 +380,77 @@ func createOutputCSV(skus []string, skuMap map[string]SKUData, filename string)
 	return nil
 }
 
+// createOutputXLSX creates a formatted XLSX report with a Data sheet and a
+// separate Summary sheet instead of the CSV summary row
+func createOutputXLSX(skus []string, skuMap map[string]SKUData, aliases map[string]string, filename string) error {
+	report := xlsxReport{
+		Headers:   []string{"SKU", "Thickness", "Dimension", "Weight (kg)", "Status", "Match Type", "Suggested SKU", "Weight Source"},
+		StatusCol: 4,
+	}
+
+	matcher := newSKUDataMatcher(skuMap, aliases)
+
+	foundCount := 0
+	derivedCount := 0
+	for _, sku := range skus {
+		match := matcher.Match(sku)
+		if data, exists := skuMap[match.SKU]; exists && matchFound(match.Type) {
+			report.Rows = append(report.Rows, []interface{}{
+				sku,
+				data.Thickness,
+				data.Dimension,
+				data.Weight,
+				"Found",
+				match.Type,
+				match.SuggestedSKU(),
+				data.weightSource(),
+			})
+			foundCount++
+			if data.WeightDerived {
+				derivedCount++
+			}
+		} else {
+			report.Rows = append(report.Rows, []interface{}{sku, "", "", nil, "Not Found", match.Type, match.SuggestedSKU(), ""})
+		}
+	}
+
+	report.Summary = []xlsxSummaryItem{
+		{"Total SKUs", len(skus)},
+		{"Found", foundCount},
+		{"Not Found", len(skus) - foundCount},
+		{"Match Rate (%)", matchRate(foundCount, len(skus))},
+		{"Derived Weights", derivedCount},
+	}
+
+	return writeXLSXReport(report, filename)
+}
+
 // Helper functions for JSON responses
 func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	result := ProcessResult{
+	writeJSONFailure(w, ProcessResult{
 		Success: false,
 		Message: message,
-	}
+	}, statusCode)
+}
 
+// writeJSONFailure encodes an error response that carries more than a message
+func writeJSONFailure(w http.ResponseWriter, result ProcessResult, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(result)
 }
 
 func writeJSONSuccess(w http.ResponseWriter, message, outputURL, fileName string) {
-	w.Header().Set("Content-Type", "application/json")
-
-	result := ProcessResult{
+	writeJSONResult(w, ProcessResult{
 		Success:   true,
 		Message:   message,
 		OutputURL: outputURL,
 		FileName:  fileName,
-	}
+	})
+}
 
+// writeJSONResult encodes any success response as JSON
+func writeJSONResult(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
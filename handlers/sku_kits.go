@@ -0,0 +1,116 @@
+// handlers/sku_kits.go
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const kitsSheetName = "Kits"
+
+// kitComponent is one physical item shipped as part of a kit/bundle SKU
+type kitComponent struct {
+	SKU      string
+	Quantity int
+}
+
+// loadCatalogKits reads the optional "Kits" sheet (Kit SKU, Component SKU,
+// Quantity) from an uploaded catalog workbook. Every component must exist in
+// skuMap so a kit can always be expanded into cuttable pieces.
+func loadCatalogKits(filename string, skuMap map[string]PDFSKUMapping) (map[string][]kitComponent, error) {
+	kits := make(map[string][]kitComponent)
+
+	file, err := excelize.OpenFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Excel file: %v", err)
+	}
+	defer file.Close()
+
+	if idx, _ := file.GetSheetIndex(kitsSheetName); idx < 0 {
+		return kits, nil
+	}
+
+	rows, err := file.GetRows(kitsSheetName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s sheet: %v", kitsSheetName, err)
+	}
+
+	// Skip header row
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) < 2 {
+			continue
+		}
+
+		kitSKU := strings.TrimSpace(row[0])
+		componentSKU := strings.TrimSpace(row[1])
+		if kitSKU == "" || componentSKU == "" {
+			continue
+		}
+
+		quantity := 1
+		if len(row) > 2 && strings.TrimSpace(row[2]) != "" {
+			quantity, err = strconv.Atoi(strings.TrimSpace(row[2]))
+			if err != nil || quantity < 1 {
+				return nil, fmt.Errorf("%s sheet row %d: invalid quantity %q", kitsSheetName, i+1, row[2])
+			}
+		}
+
+		if _, found := skuMap[componentSKU]; !found {
+			return nil, fmt.Errorf("%s sheet row %d: component %s of kit %s is not in the catalog",
+				kitsSheetName, i+1, componentSKU, kitSKU)
+		}
+
+		kits[kitSKU] = append(kits[kitSKU], kitComponent{SKU: componentSKU, Quantity: quantity})
+	}
+
+	return kits, nil
+}
+
+// expandKit replaces a kit line with one line per component. Lines that
+// are not kits are returned unchanged.
+func (c *pdfCatalog) expandKit(order PDFOrderData) []PDFOrderData {
+	components, isKit := c.Kits[order.CatalogSKU]
+	if !isKit {
+		return []PDFOrderData{order}
+	}
+
+	quantity := order.Quantity
+	if quantity < 1 {
+		quantity = 1
+	}
+
+	// Name the kit as the catalog does, not as printed, which may be an
+	// alias or a misspelling
+	kitSKU := order.CatalogSKU
+	if kitSKU == "" {
+		kitSKU = order.SKUID
+	}
+
+	expanded := make([]PDFOrderData, 0, len(components))
+	for _, component := range components {
+		line := order
+		line.KitSKU = kitSKU
+		line.SKUID = component.SKU
+		line.CatalogSKU = component.SKU
+		line.Quantity = component.Quantity * quantity
+
+		mapping := c.SKUs[component.SKU]
+		line.Thickness = mapping.Thickness
+		line.Dimension = mapping.Dimension
+		line.ParsedThickness = mapping.ParsedThickness
+		line.ParsedDimension = mapping.ParsedDimension
+		line.Weight = mapping.Weight
+		line.WeightDerived = mapping.WeightDerived
+		line.PackedSize = mapping.PackedSize
+		line.BinLocation = mapping.BinLocation
+		line.Custom = mapping.Custom
+
+		expanded = append(expanded, line)
+	}
+
+	return expanded
+}
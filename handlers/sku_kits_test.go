@@ -0,0 +1,32 @@
+package handlers
+
+import "testing"
+
+func TestExpandKitNamesTheCatalogKit(t *testing.T) {
+	skuMap := map[string]PDFSKUMapping{
+		"MRC-MR-0530": {SKU: "MRC-MR-0530", Thickness: "5mm", Dimension: "24x36 in"},
+		"MRC-MR-0531": {SKU: "MRC-MR-0531", Thickness: "5mm", Dimension: "36x48 in"},
+	}
+	kits := map[string][]kitComponent{
+		"MRC-KIT-01": {{SKU: "MRC-MR-0530", Quantity: 2}, {SKU: "MRC-MR-0531", Quantity: 1}},
+	}
+	catalog := newPDFCatalog(skuMap, map[string]string{"B0KITASIN": "MRC-KIT-01"}, kits)
+
+	order := PDFOrderData{OrderNumber: "111-1111111-1111111", SKUID: "B0KITASIN", Quantity: 3}
+	catalog.resolve(&order)
+	lines := catalog.expandKit(order)
+
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	for i, want := range []struct {
+		sku      string
+		quantity int
+	}{{"MRC-MR-0530", 6}, {"MRC-MR-0531", 3}} {
+		line := lines[i]
+		if line.KitSKU != "MRC-KIT-01" || line.SKUID != want.sku || line.Quantity != want.quantity {
+			t.Errorf("line %d = %s x%d from kit %s, want %s x%d from kit MRC-KIT-01",
+				i, line.SKUID, line.Quantity, line.KitSKU, want.sku, want.quantity)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// handlers/sku_learned.go
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const learnedSKUStorePath = "./data/learned_skus.json"
+
+// LearnedSKU is a catalog row added while reviewing results, for SKUs that
+// are missing from the uploaded catalog. Cells are kept as typed and parsed
+// like uploaded rows.
+type LearnedSKU struct {
+	SKU         string    `json:"sku"`
+	Thickness   string    `json:"thickness"`
+	Dimension   string    `json:"dimension"`
+	Weight      string    `json:"weight,omitempty"` // empty derives it from the Densities sheet
+	BinLocation string    `json:"bin_location,omitempty"`
+	AddedBy     string    `json:"added_by"`
+	AddedAt     time.Time `json:"added_at"`
+}
+
+type LearnedSKUListResult struct {
+	Success bool         `json:"success"`
+	SKUs    []LearnedSKU `json:"skus"`
+}
+
+// UnmatchedSKU is an identifier from a run that is not in the catalog, with
+// the closest catalog SKUs, offered for teaching
+type UnmatchedSKU struct {
+	SKU         string   `json:"sku"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// learnedSKUStore is the server-side table of learned catalog rows,
+// persisted as JSON
+type learnedSKUStore struct {
+	*jsonStore[LearnedSKU]
+}
+
+var learnedSKUs = &learnedSKUStore{newJSONStore(learnedSKUStorePath, "learned SKU store", func(l LearnedSKU) string { return l.SKU })}
+
+// Put adds or replaces a learned row
+func (s *learnedSKUStore) Put(sku LearnedSKU) error {
+	sku.AddedAt = time.Now()
+	return s.jsonStore.Put(sku)
+}
+
+// mapping reads the row's cells as loadPDFSKUMapping reads an uploaded row
+func (l LearnedSKU) mapping(densities materialDensities) (PDFSKUMapping, error) {
+	t, d, err := parseCatalogMeasurements(l.Thickness, l.Dimension)
+	if err != nil {
+		return PDFSKUMapping{}, err
+	}
+	weight, derived, err := catalogWeight(l.Weight, t, d, densities)
+	if err != nil {
+		return PDFSKUMapping{}, err
+	}
+	return PDFSKUMapping{
+		SKU:             l.SKU,
+		Thickness:       l.Thickness,
+		Dimension:       l.Dimension,
+		Weight:          weight,
+		WeightDerived:   derived,
+		BinLocation:     l.BinLocation,
+		ParsedThickness: t,
+		ParsedDimension: d,
+	}, nil
+}
+
+// mergeLearnedSKUs fills in SKUs the upload lacks from the learned rows,
+// converting each with row. Rows that no longer parse are skipped.
+func mergeLearnedSKUs[T any](skuMap map[string]T, densities materialDensities, row func(PDFSKUMapping) T) error {
+	learned, err := learnedSKUs.List()
+	if err != nil {
+		return err
+	}
+	for _, l := range learned {
+		if _, exists := skuMap[l.SKU]; exists {
+			continue
+		}
+		mapping, err := l.mapping(densities)
+		if err != nil {
+			fmt.Printf("Skipping learned SKU %s: %v\n", l.SKU, err)
+			continue
+		}
+		skuMap[l.SKU] = row(mapping)
+	}
+	return nil
+}
+
+// unmatchedSKUs lists the distinct identifiers in ids that matcher cannot
+// resolve, in order
+func unmatchedSKUs(ids []string, matcher *skuMatcher) []UnmatchedSKU {
+	var unmatched []UnmatchedSKU
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		match := matcher.Match(id)
+		if matchFound(match.Type) {
+			continue
+		}
+		u := UnmatchedSKU{SKU: id}
+		for _, s := range match.Suggestions {
+			u.Suggestions = append(u.Suggestions, s.SKU)
+		}
+		unmatched = append(unmatched, u)
+	}
+	return unmatched
+}
+
+// unmatchedOrderSKUs lists the distinct invoice SKUs in orders that are not
+// in the catalog
+func unmatchedOrderSKUs(orders []PDFOrderData, catalog *pdfCatalog) []UnmatchedSKU {
+	var ids []string
+	for _, order := range orders {
+		if !matchFound(order.MatchType) {
+			ids = append(ids, order.SKUID)
+		}
+	}
+	return unmatchedSKUs(ids, catalog.matcher)
+}
+
+// requestEditor names who is making a change: the by form field, or the
+// client's address when it is empty
+func requestEditor(r *http.Request) string {
+	if by := strings.TrimSpace(r.FormValue("by")); by != "" {
+		return by
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// LearnedSKUsHandler manages catalog rows added from the results screen.
+//
+//	GET    /learned-skus                                                  list rows
+//	POST   /learned-skus  sku, thickness, dimension, weight, binLocation, by  add or replace a row
+//	DELETE /learned-skus?sku=...                                          remove a row
+func LearnedSKUsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		skus, err := learnedSKUs.List()
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResult(w, LearnedSKUListResult{Success: true, SKUs: skus})
+
+	case "POST":
+		row := LearnedSKU{
+			SKU:         strings.TrimSpace(r.FormValue("sku")),
+			Thickness:   strings.TrimSpace(r.FormValue("thickness")),
+			Dimension:   strings.TrimSpace(r.FormValue("dimension")),
+			Weight:      strings.TrimSpace(r.FormValue("weight")),
+			BinLocation: strings.TrimSpace(r.FormValue("binLocation")),
+			AddedBy:     requestEditor(r),
+		}
+		if row.SKU == "" || row.Thickness == "" || row.Dimension == "" {
+			writeJSONError(w, "sku, thickness and dimension are required", http.StatusBadRequest)
+			return
+		}
+		if _, err := row.mapping(nil); err != nil {
+			writeJSONError(w, fmt.Sprintf("Invalid catalog row for %s: %v", row.SKU, err), http.StatusBadRequest)
+			return
+		}
+
+		if err := learnedSKUs.Put(row); err != nil {
+			writeJSONError(w, "Failed to save catalog row: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONSuccess(w, fmt.Sprintf("%s added to the catalog", row.SKU), "", "")
+
+	case "DELETE":
+		sku := strings.TrimSpace(r.URL.Query().Get("sku"))
+		if sku == "" {
+			writeJSONError(w, "sku is required", http.StatusBadRequest)
+			return
+		}
+
+		found, err := learnedSKUs.Delete(sku)
+		if err != nil {
+			writeJSONError(w, "Failed to delete catalog row: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			writeJSONError(w, "Learned SKU not found: "+sku, http.StatusNotFound)
+			return
+		}
+		writeJSONSuccess(w, sku+" removed from the learned catalog", "", "")
+
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,286 @@
+// handlers/sku_matcher.go
+package handlers
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Match types reported in the output "Match Type" column
+const (
+	matchExact      = "exact"
+	matchNormalised = "normalised"
+	matchFuzzy      = "fuzzy"
+	matchAlias      = "alias"
+	matchNone       = "none"
+)
+
+const (
+	// fuzzyDistance is the largest edit distance at which a single closest
+	// catalog SKU is reported as a fuzzy match. Fuzzy matches are never
+	// used: "MRC-MR-0531" is one edit from "MRC-MR-0530" but another product.
+	fuzzyDistance = 2
+	// suggestDistance is the largest edit distance offered as a suggestion
+	suggestDistance = 4
+	maxSuggestions  = 3
+)
+
+// knownSKUSuffixes are marketplace variants of a catalog SKU, such as
+// "MRC-MR-0530-A" or "MRC-MR-0530_NEW", that match the SKU itself
+var knownSKUSuffixes = []string{"A", "NEW"}
+
+// matchFound reports whether a match type resolves to a catalog SKU. Fuzzy
+// matches are only suggestions for review.
+func matchFound(matchType string) bool {
+	return matchType != matchNone && matchType != matchFuzzy
+}
+
+// skuMatch is the result of looking up one SKU in the catalog
+type skuMatch struct {
+	SKU         string // catalog SKU, empty when Type is matchNone or matchFuzzy
+	Type        string
+	Suggestions []skuSuggestion
+}
+
+type skuSuggestion struct {
+	SKU      string
+	Distance int
+}
+
+// skuMatcher resolves SKUs as printed on invoices or listings to catalog
+// SKUs, tolerating case, whitespace, dash, padding and small typos, and
+// translating marketplace aliases
+type skuMatcher struct {
+	catalog         map[string]bool
+	normalised      map[string]string // normalised form -> catalog SKU
+	keys            []string          // normalised forms, sorted
+	aliases         map[string]string // alias -> catalog SKU
+	aliasNormalised map[string]string // normalised alias -> catalog SKU
+	aliasPattern    *regexp.Regexp    // matches any alias or extra identifier in free text
+}
+
+// newSKUMatcher builds a matcher over the catalog SKUs. Aliases pointing at
+// SKUs missing from the catalog are ignored.
+func newSKUMatcher(skus []string, aliases map[string]string) *skuMatcher {
+	m := &skuMatcher{
+		catalog:         make(map[string]bool, len(skus)),
+		normalised:      make(map[string]string, len(skus)),
+		aliases:         make(map[string]string, len(aliases)),
+		aliasNormalised: make(map[string]string, len(aliases)),
+	}
+
+	sort.Strings(skus)
+	for _, sku := range skus {
+		m.catalog[sku] = true
+		norm := normaliseSKU(sku)
+		if _, exists := m.normalised[norm]; !exists {
+			m.normalised[norm] = sku
+			m.keys = append(m.keys, norm)
+		}
+	}
+	sort.Strings(m.keys)
+
+	var aliasList []string
+	for alias, sku := range aliases {
+		if !m.catalog[sku] {
+			continue
+		}
+		m.aliases[alias] = sku
+		m.aliasNormalised[normaliseSKU(alias)] = sku
+		aliasList = append(aliasList, alias)
+	}
+	m.aliasPattern = buildAliasPattern(aliasList)
+
+	return m
+}
+
+// addTextIdentifiers makes findSKUIdentifiers pick up ids verbatim in
+// addition to the aliases, for catalog entries such as kit SKUs that don't
+// follow the usual SKU pattern
+func (m *skuMatcher) addTextIdentifiers(ids []string) {
+	all := make([]string, 0, len(m.aliases)+len(ids))
+	for alias := range m.aliases {
+		all = append(all, alias)
+	}
+	all = append(all, ids...)
+	m.aliasPattern = buildAliasPattern(all)
+}
+
+// buildAliasPattern returns a regexp matching any of the aliases as a whole
+// token, longest first, or nil when there are none
+func buildAliasPattern(aliases []string) *regexp.Regexp {
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	sort.Slice(aliases, func(i, j int) bool {
+		if len(aliases[i]) != len(aliases[j]) {
+			return len(aliases[i]) > len(aliases[j])
+		}
+		return aliases[i] < aliases[j]
+	})
+
+	parts := make([]string, len(aliases))
+	for i, alias := range aliases {
+		part := regexp.QuoteMeta(alias)
+		if isWordByte(alias[0]) {
+			part = `\b` + part
+		}
+		if isWordByte(alias[len(alias)-1]) {
+			part += `\b`
+		}
+		parts[i] = part
+	}
+
+	return regexp.MustCompile(strings.Join(parts, "|"))
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Match looks up sku, trying an exact match or alias, then the normalised
+// form of either, with or without a known marketplace suffix. Otherwise it
+// ranks edit-distance candidates as suggestions.
+func (m *skuMatcher) Match(sku string) skuMatch {
+	if m.catalog[sku] {
+		return skuMatch{SKU: sku, Type: matchExact}
+	}
+	if catalogSKU, ok := m.aliases[sku]; ok {
+		return skuMatch{SKU: catalogSKU, Type: matchAlias}
+	}
+
+	norm := normaliseSKU(sku)
+	if catalogSKU, ok := m.normalised[norm]; ok {
+		return skuMatch{SKU: catalogSKU, Type: matchNormalised}
+	}
+	if catalogSKU, ok := m.aliasNormalised[norm]; ok {
+		return skuMatch{SKU: catalogSKU, Type: matchAlias}
+	}
+	for _, suffix := range knownSKUSuffixes {
+		base, found := strings.CutSuffix(norm, "-"+suffix)
+		if !found {
+			continue
+		}
+		if catalogSKU, ok := m.normalised[base]; ok {
+			return skuMatch{SKU: catalogSKU, Type: matchNormalised}
+		}
+		if catalogSKU, ok := m.aliasNormalised[base]; ok {
+			return skuMatch{SKU: catalogSKU, Type: matchAlias}
+		}
+	}
+
+	suggestions := m.suggest(norm)
+	result := skuMatch{Type: matchNone, Suggestions: suggestions}
+
+	// Flag an unambiguous closest candidate, leaving it to review
+	if len(suggestions) > 0 && suggestions[0].Distance <= fuzzyDistance {
+		if len(suggestions) == 1 || suggestions[1].Distance > suggestions[0].Distance {
+			result.Type = matchFuzzy
+		}
+	}
+
+	return result
+}
+
+// SuggestedSKU returns the value for the "Suggested SKU" column: the catalog
+// SKU actually used for inexact matches, or the ranked suggestions otherwise
+func (r skuMatch) SuggestedSKU() string {
+	switch r.Type {
+	case matchExact:
+		return ""
+	case matchNormalised, matchAlias:
+		return r.SKU
+	}
+
+	var names []string
+	for _, s := range r.Suggestions {
+		names = append(names, s.SKU)
+	}
+	return strings.Join(names, "; ")
+}
+
+func (m *skuMatcher) suggest(norm string) []skuSuggestion {
+	var suggestions []skuSuggestion
+
+	for _, key := range m.keys {
+		distance := levenshtein(norm, key)
+
+		// A marketplace suffix such as "-A" or "_NEW" on an otherwise
+		// exact SKU ranks ahead of genuine typos
+		if strings.HasPrefix(norm, key+"-") {
+			distance = 1
+		}
+
+		if distance <= suggestDistance {
+			suggestions = append(suggestions, skuSuggestion{SKU: m.normalised[key], Distance: distance})
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Distance < suggestions[j].Distance
+	})
+
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions
+}
+
+var (
+	digitRunRegex   = regexp.MustCompile(`\d+`)
+	dashSpaceRegex  = regexp.MustCompile(`\s*-\s*`)
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// normaliseSKU upper-cases sku, folds Unicode dashes, underscores and inner
+// whitespace to "-" and strips zero-padding from numeric parts
+func normaliseSKU(sku string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(sku) {
+		switch {
+		case r == '_' || unicode.Is(unicode.Pd, r) || r == '−':
+			b.WriteRune('-')
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+
+	norm := dashSpaceRegex.ReplaceAllString(b.String(), "-")
+	norm = whitespaceRegex.ReplaceAllString(norm, "-")
+
+	return digitRunRegex.ReplaceAllStringFunc(norm, func(digits string) string {
+		trimmed := strings.TrimLeft(digits, "0")
+		if trimmed == "" {
+			return "0"
+		}
+		return trimmed
+	})
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(min(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchNormalises(t *testing.T) {
+	m := newSKUMatcher([]string{"MRC-MR-0530"}, nil)
+
+	for _, sku := range []string{
+		"mrc-mr-0530",
+		" MRC MR 0530 ",
+		"MRC–MR–0530", // en dashes
+		"MRC_MR_530",
+		"MRC-MR-00530",
+		"MRC-MR-0530-A",
+		"MRC-MR-0530_NEW",
+		"mrc-mr-0530-new",
+	} {
+		got := m.Match(sku)
+		if got.Type != matchNormalised || got.SKU != "MRC-MR-0530" {
+			t.Errorf("Match(%q) = %s %q, want normalised MRC-MR-0530", sku, got.Type, got.SKU)
+		}
+	}
+}
+
+func TestMatchDoesNotAcceptNearMisses(t *testing.T) {
+	m := newSKUMatcher([]string{"MRC-MR-0530", "MRC-MR-0700"}, nil)
+
+	for _, sku := range []string{
+		"MRC-MR-0531", // neighbouring product
+		"MRC-MR-0503", // transposed digits
+		"MRC-MR-1530",
+		"MRC-MR-0530-XL", // unknown suffix
+	} {
+		got := m.Match(sku)
+		if matchFound(got.Type) || got.SKU != "" {
+			t.Errorf("Match(%q) = %s %q, want no catalog SKU", sku, got.Type, got.SKU)
+		}
+		if !strings.Contains(got.SuggestedSKU(), "MRC-MR-0530") {
+			t.Errorf("Match(%q) suggests %q, want MRC-MR-0530 among them", sku, got.SuggestedSKU())
+		}
+	}
+
+	if got := m.Match("MRC-MR-0531"); got.Type != matchFuzzy {
+		t.Errorf("Match(MRC-MR-0531) type = %s, want fuzzy for a single close candidate", got.Type)
+	}
+}
+
+func TestResolveLeavesNearMissNotFound(t *testing.T) {
+	catalog := testCatalog("MRC-MR-0530")
+
+	order := PDFOrderData{SKUID: "MRC-MR-0531"}
+	catalog.resolve(&order)
+
+	if order.CatalogSKU != "" || order.Thickness != "N/A" || order.Dimension != "N/A" {
+		t.Errorf("resolved near miss to %q (%s, %s), want N/A", order.CatalogSKU, order.Thickness, order.Dimension)
+	}
+	if order.SuggestedSKU != "MRC-MR-0530" {
+		t.Errorf("SuggestedSKU = %q, want MRC-MR-0530", order.SuggestedSKU)
+	}
+	if lineProblem(order) == "" {
+		t.Error("near miss is not flagged as a problem")
+	}
+}
+
+func TestSKUReportMarksNearMissNotFound(t *testing.T) {
+	skuMap := map[string]SKUData{"MRC-MR-0530": {SKU: "MRC-MR-0530", Thickness: "5mm", Dimension: "24x36 in"}}
+	filename := filepath.Join(t.TempDir(), "report.csv")
+
+	if err := createOutputCSV([]string{"MRC-MR-0531"}, skuMap, nil, filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := strings.Split(string(data), "\n")[1]
+	if !strings.HasPrefix(row, "MRC-MR-0531,,,,Not Found,fuzzy,MRC-MR-0530,") {
+		t.Errorf("report row = %q, want MRC-MR-0531 Not Found with MRC-MR-0530 suggested", row)
+	}
+}
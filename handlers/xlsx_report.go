@@ -0,0 +1,187 @@
+// handlers/xlsx_report.go
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	xlsxDataSheet    = "Data"
+	xlsxSummarySheet = "Summary"
+)
+
+// xlsxSummaryItem is a single label/value line on the Summary sheet
+type xlsxSummaryItem struct {
+	Label string
+	Value interface{}
+}
+
+// xlsxTable is a sheet of rows under a header
+type xlsxTable struct {
+	Name      string
+	Headers   []string
+	Rows      [][]interface{}
+	StatusCol int
+}
+
+// xlsxReport describes a workbook with a Data sheet and a Summary sheet.
+// StatusCol is the zero-based column holding the match status; rows whose
+// status equals "Not Found" are filled red. Use -1 to disable highlighting.
+// Extra tables are added as further sheets after Summary, formatted like
+// the Data sheet.
+type xlsxReport struct {
+	Headers   []string
+	Rows      [][]interface{}
+	StatusCol int
+	Summary   []xlsxSummaryItem
+	Extra     []xlsxTable
+}
+
+// xlsxStyles holds the style IDs shared by all table sheets
+type xlsxStyles struct {
+	header   int
+	notFound int
+}
+
+// writeXLSXReport writes the report to filename with a frozen header row,
+// an autofilter over the data range and a separate Summary sheet
+func writeXLSXReport(report xlsxReport, filename string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", xlsxDataSheet); err != nil {
+		return fmt.Errorf("failed to name data sheet: %v", err)
+	}
+
+	var styles xlsxStyles
+	var err error
+
+	styles.header, err = f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"D9E1F2"}, Pattern: 1},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create header style: %v", err)
+	}
+
+	styles.notFound, err = f.NewStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"FFC7CE"}, Pattern: 1},
+		Font: &excelize.Font{Color: "9C0006"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create highlight style: %v", err)
+	}
+
+	data := xlsxTable{
+		Name:      xlsxDataSheet,
+		Headers:   report.Headers,
+		Rows:      report.Rows,
+		StatusCol: report.StatusCol,
+	}
+	if err := writeXLSXTable(f, data, styles); err != nil {
+		return err
+	}
+
+	// Summary sheet
+	if _, err := f.NewSheet(xlsxSummarySheet); err != nil {
+		return fmt.Errorf("failed to create summary sheet: %v", err)
+	}
+	for i, item := range report.Summary {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		values := []interface{}{item.Label, item.Value}
+		if err := f.SetSheetRow(xlsxSummarySheet, cell, &values); err != nil {
+			return fmt.Errorf("failed to write summary: %v", err)
+		}
+	}
+	f.SetColWidth(xlsxSummarySheet, "A", "A", 24)
+	f.SetColWidth(xlsxSummarySheet, "B", "B", 14)
+
+	for _, table := range report.Extra {
+		if _, err := f.NewSheet(table.Name); err != nil {
+			return fmt.Errorf("failed to create %s sheet: %v", table.Name, err)
+		}
+		if err := writeXLSXTable(f, table, styles); err != nil {
+			return err
+		}
+	}
+
+	f.SetActiveSheet(0)
+
+	if err := f.SaveAs(filename); err != nil {
+		return fmt.Errorf("failed to save XLSX file: %v", err)
+	}
+
+	return nil
+}
+
+// writeXLSXTable fills an existing sheet with a styled header, the rows, a
+// frozen header pane and an autofilter
+func writeXLSXTable(f *excelize.File, table xlsxTable, styles xlsxStyles) error {
+	sheet := table.Name
+
+	// Header row
+	header := make([]interface{}, len(table.Headers))
+	for i, h := range table.Headers {
+		header[i] = h
+	}
+	if err := f.SetSheetRow(sheet, "A1", &header); err != nil {
+		return fmt.Errorf("failed to write %s header: %v", sheet, err)
+	}
+
+	lastCol, err := excelize.ColumnNumberToName(len(table.Headers))
+	if err != nil {
+		return fmt.Errorf("invalid column count: %v", err)
+	}
+	if err := f.SetCellStyle(sheet, "A1", lastCol+"1", styles.header); err != nil {
+		return fmt.Errorf("failed to style %s header: %v", sheet, err)
+	}
+
+	// Data rows
+	for i, row := range table.Rows {
+		rowNum := i + 2
+		cell, _ := excelize.CoordinatesToCellName(1, rowNum)
+		values := row
+		if err := f.SetSheetRow(sheet, cell, &values); err != nil {
+			return fmt.Errorf("failed to write %s row %d: %v", sheet, rowNum, err)
+		}
+
+		if table.StatusCol >= 0 && table.StatusCol < len(row) {
+			if status, ok := row[table.StatusCol].(string); ok && status == "Not Found" {
+				if err := f.SetCellStyle(sheet, cell, fmt.Sprintf("%s%d", lastCol, rowNum), styles.notFound); err != nil {
+					return fmt.Errorf("failed to style %s row %d: %v", sheet, rowNum, err)
+				}
+			}
+		}
+	}
+
+	// Freeze the header row
+	err = f.SetPanes(sheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to freeze %s header: %v", sheet, err)
+	}
+
+	// Autofilter over the full data range
+	filterRange := fmt.Sprintf("A1:%s%d", lastCol, len(table.Rows)+1)
+	if err := f.AutoFilter(sheet, filterRange, nil); err != nil {
+		return fmt.Errorf("failed to add %s autofilter: %v", sheet, err)
+	}
+
+	f.SetColWidth(sheet, "A", lastCol, 18)
+
+	return nil
+}
+
+// matchRate returns found/total as a percentage rounded to one decimal place
+func matchRate(found, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(int(float64(found)/float64(total)*1000+0.5)) / 10
+}
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"html/template"
 	"log"
@@ -19,13 +20,21 @@ const (
 )
 
 func main() {
+	flag.StringVar(&handlers.DefaultPDFPassword, "pdf-password", "", "Default password for encrypted PDF invoices")
+	flag.Float64Var(&handlers.DefaultVolumetricDivisor, "volumetric-divisor", 5000, "Courier volumetric divisor in cm³ per kg")
+	flag.StringVar(&handlers.PDFFontPath, "pdf-font", "", "TrueType font embedded in generated PDFs (default ./fonts/DejaVuSans.ttf)")
+	flag.StringVar(&handlers.PDFBoldFontPath, "pdf-font-bold", "", "Bold TrueType font embedded in generated PDFs")
+	flag.StringVar(&handlers.PDFFallbackFontPath, "pdf-font-fallback", "", "TrueType font for characters the PDF font lacks (default ./fonts/NotoSansDevanagari-Regular.ttf)")
+	flag.Parse()
+
 	// Create necessary directories
 	os.MkdirAll(uploadDir, 0755)
 	os.MkdirAll(outputDir, 0755)
 	os.MkdirAll("templates", 0755)
 
-	// Create template file if it doesn't exist
+	// Create template files if they don't exist
 	createTemplateFile()
+	createPackStationTemplate()
 
 	// Serve static files and outputs
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static/"))))
@@ -35,6 +44,12 @@ func main() {
 	http.HandleFunc("/", indexHandler)
 	http.HandleFunc("/process-pdf", handlers.ProcessPDFHandler)
 	http.HandleFunc("/process-sku", handlers.ProcessSKUHandler)
+	http.HandleFunc("/aliases", handlers.AliasesHandler)
+	http.HandleFunc("/learned-skus", handlers.LearnedSKUsHandler)
+	http.HandleFunc("/overlay-presets", handlers.OverlayPresetsHandler)
+	http.HandleFunc("/pack-station", packStationHandler)
+	http.HandleFunc("/verify", handlers.VerifyHandler)
+	http.HandleFunc("/verify/export", handlers.VerifyExportHandler)
 
 	fmt.Printf("🚀 PDF & SKU Processor Server starting on http://0.0.0.0:8080\n")
 	fmt.Printf("🌐 External access: http://YOUR_SERVER_IP:8080\n")
@@ -65,6 +80,26 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func packStationHandler(w http.ResponseWriter, r *http.Request) {
+	tmplFile := filepath.Join("templates", "pack_station.html")
+	tmpl, err := template.ParseFiles(tmplFile)
+	if err != nil {
+		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Title string
+	}{
+		Title: "Pack Station",
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
 func createTemplateFile() {
 	templatePath := filepath.Join("templates", "index.html")
 
@@ -270,6 +305,109 @@ func createTemplateFile() {
             box-shadow: 0 0 0 3px rgba(102, 126, 234, 0.1);
         }
         
+        .password-input {
+            width: 100%;
+            padding: 10px 15px;
+            border: 2px solid #ddd;
+            border-radius: 8px;
+            font-size: 0.9em;
+            transition: border-color 0.3s ease;
+        }
+        
+        .password-input:focus {
+            outline: none;
+            border-color: #667eea;
+        }
+        
+        .select-input {
+            width: 100%;
+            padding: 10px 15px;
+            border: 2px solid #ddd;
+            border-radius: 8px;
+            font-size: 0.9em;
+            background: white;
+        }
+        
+        .checkbox-option {
+            display: flex;
+            align-items: center;
+            gap: 8px;
+            margin-bottom: 10px;
+            font-size: 0.9em;
+            color: #333;
+        }
+        
+        .diagnostics {
+            margin-top: 15px;
+            display: none;
+            overflow-x: auto;
+        }
+        
+        .diagnostics table {
+            width: 100%;
+            border-collapse: collapse;
+            font-size: 0.8em;
+        }
+        
+        .diagnostics th, .diagnostics td {
+            border: 1px solid #ddd;
+            padding: 4px 6px;
+            text-align: left;
+            vertical-align: top;
+        }
+        
+        .diagnostics th {
+            background: #f0f2fa;
+        }
+        
+        .diagnostics .warning {
+            color: #a94400;
+        }
+        
+        .preview-problem td {
+            background: #fde2e2;
+        }
+        
+        .preview-input {
+            width: 130px;
+            padding: 3px 6px;
+            border: 1px solid #ccc;
+            border-radius: 4px;
+        }
+        
+        .teach-input {
+            width: 90px;
+            padding: 3px 6px;
+            border: 1px solid #ccc;
+            border-radius: 4px;
+        }
+        
+        .suggestion-btn {
+            margin: 2px 2px 0 0;
+            padding: 1px 6px;
+            border: 1px solid #667eea;
+            border-radius: 4px;
+            background: white;
+            color: #667eea;
+            font-size: 0.9em;
+            cursor: pointer;
+        }
+        
+        .overlay-style summary {
+            cursor: pointer;
+            font-size: 0.9em;
+            color: #333;
+            margin-bottom: 5px;
+        }
+        
+        .inline-input {
+            width: 90px;
+            padding: 6px 10px;
+            border: 2px solid #ddd;
+            border-radius: 6px;
+            font-size: 0.9em;
+        }
+        
         .char-count {
             margin-top: 5px;
             font-size: 0.8em;
@@ -414,6 +552,33 @@ func createTemplateFile() {
                         <div id="pdf-mapping-name" class="file-name"></div>
                     </div>
                     
+                    <div class="upload-section">
+                        <h3>Dimension Units</h3>
+                        <select id="pdf-dimension-unit" name="dimensionUnit" class="select-input">
+                            <option value="original" selected>As in catalog</option>
+                            <option value="in">Inches</option>
+                            <option value="cm">Centimetres</option>
+                            <option value="both">Inches and centimetres</option>
+                        </select>
+                    </div>
+                    
+                    <div class="output-mode">
+                        <h4>Shipping</h4>
+                        <label class="checkbox-option">
+                            <input type="checkbox" id="shipment-summary" name="shipmentSummary">
+                            Add per-order shipment weights (CSV/XLSX)
+                        </label>
+                        <label class="checkbox-option">
+                            Volumetric divisor (cm³/kg)
+                            <input type="number" id="volumetric-divisor" name="volumetricDivisor" class="inline-input" value="5000" min="1">
+                        </label>
+                    </div>
+                    
+                    <div class="upload-section">
+                        <h3>PDF Password (optional)</h3>
+                        <input type="password" id="pdf-password" name="password" class="password-input" placeholder="Only needed for encrypted invoices" autocomplete="off">
+                    </div>
+                    
                     <div class="output-mode">
                         <h4>Output Mode</h4>
                         <div class="radio-group">
@@ -421,11 +586,137 @@ func createTemplateFile() {
                                 <input type="radio" id="csv-mode" name="outputMode" value="csv" checked>
                                 <label for="csv-mode">📊 CSV Report</label>
                             </div>
+                            <div class="radio-option">
+                                <input type="radio" id="xlsx-mode" name="outputMode" value="xlsx">
+                                <label for="xlsx-mode">📗 XLSX Report</label>
+                            </div>
                             <div class="radio-option">
                                 <input type="radio" id="overlay-mode" name="outputMode" value="overlay">
                                 <label for="overlay-mode">📄 PDF Overlay</label>
                             </div>
+                            <div class="radio-option">
+                                <input type="radio" id="cutlist-mode" name="outputMode" value="cutlist">
+                                <label for="cutlist-mode">✂️ Cut List</label>
+                            </div>
+                            <div class="radio-option">
+                                <input type="radio" id="nesting-mode" name="outputMode" value="nesting">
+                                <label for="nesting-mode">🧩 Nesting Plan</label>
+                            </div>
+                            <div class="radio-option">
+                                <input type="radio" id="picklist-mode" name="outputMode" value="picklist">
+                                <label for="picklist-mode">📋 Pick List</label>
+                            </div>
                         </div>
+                        <label class="checkbox-option" style="margin-top: 10px;">
+                            Cut list format
+                            <select id="cutlist-format" name="cutListFormat" class="inline-input">
+                                <option value="csv" selected>CSV</option>
+                                <option value="xlsx">XLSX</option>
+                                <option value="pdf">PDF</option>
+                            </select>
+                        </label>
+                        <label class="checkbox-option">
+                            Stamp annotations on
+                            <select id="stamp-on" name="stampOn" class="inline-input" style="width: 150px;">
+                                <option value="invoice" selected>Invoice page</option>
+                                <option value="label">Shipping label</option>
+                                <option value="both">Label and invoice</option>
+                            </select>
+                        </label>
+                        <label class="checkbox-option">
+                            Overlay page order
+                            <select id="page-sort" name="pageSort" class="inline-input" style="width: 150px;">
+                                <option value="none" selected>As in invoice</option>
+                                <option value="thickness">By thickness</option>
+                                <option value="dimension">By dimension</option>
+                                <option value="sku">By SKU</option>
+                                <option value="date">By order date</option>
+                            </select>
+                        </label>
+                        <label class="checkbox-option">
+                            <input type="checkbox" id="separator-pages" name="separatorPages">
+                            Add a separator page before each group
+                        </label>
+                        <label class="checkbox-option">
+                            <input type="checkbox" id="order-barcode" name="orderBarcode">
+                            Stamp a Code128 barcode of the order number
+                        </label>
+                        <label class="checkbox-option">
+                            <input type="checkbox" id="qr-code" name="qrCode">
+                            Stamp a QR code of order, SKU and dimension
+                        </label>
+                        <label class="checkbox-option">
+                            <input type="checkbox" id="highlight-problems" name="highlightProblems" checked>
+                            Mark unmatched and multi-SKU pages in red, with a summary cover page
+                        </label>
+                        <label class="checkbox-option">
+                            Split overlay into
+                            <select id="split-by" name="splitBy" class="inline-input" style="width: 150px;">
+                                <option value="none" selected>One PDF</option>
+                                <option value="order">ZIP, file per order</option>
+                                <option value="thickness">ZIP, file per thickness</option>
+                            </select>
+                        </label>
+                        <details class="overlay-style">
+                            <summary>Overlay text and style</summary>
+                            <label class="checkbox-option" style="margin-top: 10px;">
+                                Preset
+                                <select id="overlay-preset" name="overlayPreset" class="inline-input" style="width: 150px;">
+                                    <option value="">Default</option>
+                                </select>
+                            </label>
+                            <textarea id="overlay-template" name="overlayTemplate" class="text-input" style="height: 70px;" placeholder="Template, e.g. {{"{{.Quantity}} x {{.Thickness}} {{.Dimension}} {{index .Custom \"Finish\"}}"}}"></textarea>
+                            <label class="checkbox-option" style="margin-top: 10px;">
+                                Font
+                                <select id="overlay-font" name="overlayFont" class="inline-input" style="width: 120px;">
+                                    <option value="">Preset</option>
+                                    <option value="Default">Default (UTF-8)</option>
+                                    <option value="Arial">Arial</option>
+                                    <option value="Helvetica">Helvetica</option>
+                                    <option value="Times">Times</option>
+                                    <option value="Courier">Courier</option>
+                                </select>
+                                Size
+                                <input type="number" id="overlay-font-size" name="overlayFontSize" class="inline-input" placeholder="14" min="6" max="36">
+                            </label>
+                            <label class="checkbox-option">
+                                Colour
+                                <input type="text" id="overlay-color" name="overlayColor" class="inline-input" placeholder="#000000">
+                                Background opacity
+                                <input type="number" id="overlay-opacity" name="overlayOpacity" class="inline-input" placeholder="0.9" min="0" max="1" step="0.05">
+                            </label>
+                            <label class="checkbox-option">
+                                Position
+                                <select id="overlay-anchor" name="overlayAnchor" class="inline-input" style="width: 150px;">
+                                    <option value="">Preset</option>
+                                    <option value="bottom-left">Bottom left</option>
+                                    <option value="bottom-right">Bottom right</option>
+                                    <option value="top-left">Top left</option>
+                                    <option value="top-right">Top right</option>
+                                </select>
+                            </label>
+                            <label class="checkbox-option">
+                                <input type="text" id="overlay-preset-name" class="inline-input" style="width: 150px;" placeholder="Preset name">
+                                <button type="button" id="save-overlay-preset" class="inline-input" style="width: auto; cursor: pointer;">💾 Save as preset</button>
+                                <span id="overlay-preset-status"></span>
+                            </label>
+                        </details>
+                    </div>
+                    
+                    <div class="output-mode">
+                        <h4>Review</h4>
+                        <label class="checkbox-option">
+                            <input type="checkbox" id="preview-orders" name="preview" checked>
+                            Review and correct the extracted orders before creating the output
+                        </label>
+                    </div>
+                    
+                    <div class="output-mode">
+                        <h4>Troubleshooting</h4>
+                        <label class="checkbox-option">
+                            <input type="checkbox" id="diagnostics" name="diagnostics">
+                            Show what was found on each page (also as JSON)
+                        </label>
                     </div>
                     
                     <button type="submit" class="process-btn" id="pdf-btn">
@@ -433,6 +724,9 @@ func createTemplateFile() {
                     </button>
                     
                     <div id="pdf-status" class="status-message"></div>
+                    <div id="pdf-preview" class="diagnostics"></div>
+                    <div id="pdf-teach" class="diagnostics"></div>
+                    <div id="pdf-diagnostics" class="diagnostics"></div>
                 </form>
             </div>
             
@@ -476,11 +770,36 @@ The system will extract all 'SKU: [value]' patterns from your text."
                         <div id="sku-mapping-name" class="file-name"></div>
                     </div>
                     
+                    <div class="upload-section">
+                        <h3>Dimension Units</h3>
+                        <select id="sku-dimension-unit" name="dimensionUnit" class="select-input">
+                            <option value="original" selected>As in catalog</option>
+                            <option value="in">Inches</option>
+                            <option value="cm">Centimetres</option>
+                            <option value="both">Inches and centimetres</option>
+                        </select>
+                    </div>
+                    
+                    <div class="output-mode">
+                        <h4>Output Format</h4>
+                        <div class="radio-group">
+                            <div class="radio-option">
+                                <input type="radio" id="sku-csv-format" name="outputFormat" value="csv" checked>
+                                <label for="sku-csv-format">📊 CSV Report</label>
+                            </div>
+                            <div class="radio-option">
+                                <input type="radio" id="sku-xlsx-format" name="outputFormat" value="xlsx">
+                                <label for="sku-xlsx-format">📗 XLSX Report</label>
+                            </div>
+                        </div>
+                    </div>
+                    
                     <button type="submit" class="process-btn" id="sku-btn">
                         🏷️ Extract SKUs
                     </button>
                     
                     <div id="sku-status" class="status-message"></div>
+                    <div id="sku-teach" class="diagnostics"></div>
                 </form>
             </div>
         </div>
@@ -522,7 +841,7 @@ The system will extract all 'SKU: [value]' patterns from your text."
         });
         
         // Status message helper
-        function showStatus(statusId, type, message, downloadUrl = null) {
+        function showStatus(statusId, type, message, downloadUrl = null, downloads = []) {
             const statusDiv = document.getElementById(statusId);
             statusDiv.className = 'status-message status-' + type;
             
@@ -530,16 +849,216 @@ The system will extract all 'SKU: [value]' patterns from your text."
             if (downloadUrl) {
                 content += '<br><a href="' + downloadUrl + '" class="download-link" download>📥 Download Result</a>';
             }
+            (downloads || []).forEach(function(link) {
+                content += '<br><a href="' + link.url + '" class="download-link" download>📥 ' + link.label + '</a>';
+            });
             
             statusDiv.innerHTML = content;
             statusDiv.style.display = 'block';
         }
         
+        function escapeHTML(value) {
+            return String(value).replace(/[&<>"']/g, function(c) {
+                return {'&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'}[c];
+            });
+        }
+        
+        // Extraction diagnostics: what each page yielded and why
+        function showDiagnostics(diag) {
+            const div = document.getElementById('pdf-diagnostics');
+            if (!diag) {
+                div.style.display = 'none';
+                div.innerHTML = '';
+                return;
+            }
+            
+            let html = '<h4>Extraction diagnostics: ' + diag.lines + ' lines</h4>';
+            if (diag.fallbacks) {
+                html += '<p>Fallbacks used: ' + diag.fallbacks.map(escapeHTML).join(', ') + '</p>';
+            }
+            (diag.warnings || []).forEach(function(warning) {
+                html += '<p class="warning">⚠️ ' + escapeHTML(warning) + '</p>';
+            });
+            
+            html += '<table><tr><th>Page</th><th>Type</th><th>Text</th><th>Order numbers</th><th>SKUs</th><th>Paired</th><th>Warnings</th></tr>';
+            (diag.pages || []).forEach(function(page) {
+                let source = page.text_source + ', ' + page.text_length + ' chars';
+                if (page.text_source === 'OCR') {
+                    source += ', ' + page.ocr_confidence.toFixed(1) + '%';
+                }
+                const pairs = page.pairs.map(function(pair) {
+                    return escapeHTML(pair.order_number + ' → ' + pair.sku + ' (' + pair.match_type + ')' + (pair.continued ? ', continued' : ''));
+                });
+                html += '<tr><td>' + page.page + '</td><td>' + escapeHTML(page.type) + '</td><td>' + escapeHTML(source) + '</td>' +
+                    '<td>' + page.order_numbers.map(escapeHTML).join('<br>') + '</td>' +
+                    '<td>' + page.skus.map(escapeHTML).join('<br>') + '</td>' +
+                    '<td>' + pairs.join('<br>') + '</td>' +
+                    '<td class="warning">' + (page.warnings || []).map(escapeHTML).join('<br>') + '</td></tr>';
+            });
+            html += '</table>';
+            
+            if (diag.fallback_pairs) {
+                html += '<p>Paired across the whole text: ' + diag.fallback_pairs.map(function(pair) {
+                    return escapeHTML(pair.order_number + ' → ' + pair.sku);
+                }).join(', ') + '</p>';
+            }
+            
+            div.innerHTML = html;
+            div.style.display = 'block';
+        }
+        
+        // Teach the catalog: save unmatched SKUs as aliases or new catalog rows
+        function showTeachPanel(divId, unmatched, mappingInputId) {
+            const div = document.getElementById(divId);
+            if (!unmatched || unmatched.length === 0) {
+                div.style.display = 'none';
+                div.innerHTML = '';
+                return;
+            }
+            
+            let html = '<h4>Teach the catalog</h4>' +
+                '<p>Save each SKU not found as an alias of a catalog SKU, or add it as a new catalog row. Later runs pick these up automatically.</p>' +
+                '<label class="checkbox-option">Your name <input type="text" class="teach-input teach-by" style="width: 150px;" value="' + escapeHTML(localStorage.getItem('catalogEditor') || '') + '"></label>' +
+                '<table><tr><th>SKU</th><th>Alias of</th><th>Or new row: thickness, dimension, weight, bin</th><th></th></tr>';
+            unmatched.forEach(function(item, i) {
+                const listId = divId + '-suggestions-' + i;
+                html += '<tr data-sku="' + escapeHTML(item.sku) + '"><td>' + escapeHTML(item.sku) + '</td>' +
+                    '<td><datalist id="' + listId + '">' + (item.suggestions || []).map(function(sku) {
+                        return '<option value="' + escapeHTML(sku) + '">';
+                    }).join('') + '</datalist>' +
+                    '<input type="text" class="teach-input" style="width: 130px;" data-field="target" list="' + listId + '" value="' + escapeHTML((item.suggestions || [''])[0]) + '"> ' +
+                    '<button type="button" class="suggestion-btn" data-action="alias">Save alias</button></td>' +
+                    '<td><input type="text" class="teach-input" data-field="thickness" placeholder="5mm"> ' +
+                    '<input type="text" class="teach-input" data-field="dimension" placeholder="24x36 in"> ' +
+                    '<input type="text" class="teach-input" style="width: 60px;" data-field="weight" placeholder="kg"> ' +
+                    '<input type="text" class="teach-input" style="width: 60px;" data-field="binLocation" placeholder="Bin"> ' +
+                    '<button type="button" class="suggestion-btn" data-action="row">Add to catalog</button></td>' +
+                    '<td class="teach-status"></td></tr>';
+            });
+            html += '</table>';
+            
+            div.innerHTML = html;
+            div.style.display = 'block';
+            
+            div.querySelector('.teach-by').addEventListener('change', function() {
+                localStorage.setItem('catalogEditor', this.value.trim());
+            });
+            div.querySelectorAll('button[data-action]').forEach(function(button) {
+                button.addEventListener('click', async function() {
+                    const row = this.closest('tr');
+                    const status = row.querySelector('.teach-status');
+                    const field = function(name) {
+                        return row.querySelector('[data-field="' + name + '"]').value.trim();
+                    };
+                    
+                    const formData = new FormData();
+                    formData.set('by', div.querySelector('.teach-by').value.trim());
+                    let url = '/aliases';
+                    if (this.dataset.action === 'alias') {
+                        formData.set('alias', row.dataset.sku);
+                        formData.set('sku', field('target'));
+                        formData.set('source', 'Review');
+                        // The target is checked against the catalog used for the run
+                        const mapping = document.getElementById(mappingInputId).files[0];
+                        if (mapping) {
+                            formData.set('mapping', mapping);
+                        }
+                    } else {
+                        url = '/learned-skus';
+                        formData.set('sku', row.dataset.sku);
+                        ['thickness', 'dimension', 'weight', 'binLocation'].forEach(function(name) {
+                            formData.set(name, field(name));
+                        });
+                    }
+                    
+                    try {
+                        const response = await fetch(url, {method: 'POST', body: formData});
+                        const result = await response.json();
+                        status.textContent = result.success ? '✅ ' + result.message : '❌ ' + result.message;
+                    } catch (error) {
+                        status.textContent = '❌ ' + error.message;
+                    }
+                });
+            });
+        }
+        
+        // Extracted lines awaiting review
+        let pdfPreviewLines = [];
+        
+        function showPreview(preview) {
+            const div = document.getElementById('pdf-preview');
+            pdfPreviewLines = preview ? preview.lines : [];
+            if (!preview) {
+                div.style.display = 'none';
+                div.innerHTML = '';
+                return;
+            }
+            
+            let html = '<h4>Review extracted orders</h4>' +
+                '<p>Correct mis-read SKUs and quantities (0 removes a line), then confirm. Red rows are not in the catalog.</p>' +
+                '<datalist id="catalog-skus">' + preview.catalog_skus.map(function(sku) {
+                    return '<option value="' + escapeHTML(sku) + '">';
+                }).join('') + '</datalist>' +
+                '<table><tr><th>Page</th><th>Order</th><th>SKU</th><th>Match</th><th>Thickness</th><th>Dimension</th><th>Qty</th></tr>';
+            pdfPreviewLines.forEach(function(line, i) {
+                const problem = line.match_type === 'none' || line.match_type === 'fuzzy';
+                const suggestions = (line.suggestions || []).map(function(sku) {
+                    return '<button type="button" class="suggestion-btn" data-line="' + i + '" data-sku="' + escapeHTML(sku) + '">' + escapeHTML(sku) + '</button>';
+                }).join('');
+                html += '<tr' + (problem ? ' class="preview-problem"' : '') + '>' +
+                    '<td>' + line.page + '</td>' +
+                    '<td>' + escapeHTML(line.order_number) + '</td>' +
+                    '<td><input type="text" class="preview-input" list="catalog-skus" data-line="' + i + '" data-field="sku" value="' + escapeHTML(line.sku) + '">' +
+                    (line.kit_sku ? '<br><small>kit ' + escapeHTML(line.kit_sku) + '</small>' : '') +
+                    (suggestions ? '<br>' + suggestions : '') + '</td>' +
+                    '<td>' + escapeHTML(line.match_type) + '</td>' +
+                    '<td>' + escapeHTML(line.thickness || '') + '</td>' +
+                    '<td>' + escapeHTML(line.dimension || '') + '</td>' +
+                    '<td><input type="number" class="preview-input" style="width: 60px;" min="0" data-line="' + i + '" data-field="quantity" value="' + line.quantity + '"></td></tr>';
+            });
+            html += '</table><button type="button" class="process-btn" id="pdf-confirm-btn" style="margin-top: 15px;">✅ Confirm and create output</button>';
+            
+            div.innerHTML = html;
+            div.style.display = 'block';
+            
+            div.querySelectorAll('input[data-line]').forEach(function(input) {
+                input.addEventListener('change', function() {
+                    const line = pdfPreviewLines[this.dataset.line];
+                    line[this.dataset.field] = this.dataset.field === 'quantity' ? parseInt(this.value, 10) || 0 : this.value.trim();
+                });
+            });
+            div.querySelectorAll('.suggestion-btn').forEach(function(button) {
+                button.addEventListener('click', function() {
+                    pdfPreviewLines[this.dataset.line].sku = this.dataset.sku;
+                    div.querySelector('input[data-line="' + this.dataset.line + '"][data-field="sku"]').value = this.dataset.sku;
+                });
+            });
+            document.getElementById('pdf-confirm-btn').addEventListener('click', function() {
+                const formData = new FormData(document.getElementById('pdf-form'));
+                formData.delete('preview');
+                formData.set('confirmedLines', JSON.stringify(pdfPreviewLines));
+                submitPDF(formData);
+            });
+        }
+        
+        // Shipment weights are only written alongside CSV and XLSX reports
+        document.querySelectorAll('input[name="outputMode"]').forEach(function(radio) {
+            radio.addEventListener('change', function() {
+                const shipments = document.getElementById('shipment-summary');
+                shipments.disabled = this.value !== 'csv' && this.value !== 'xlsx';
+                if (shipments.disabled) {
+                    shipments.checked = false;
+                }
+            });
+        });
+        
         // PDF Form Handler
-        document.getElementById('pdf-form').addEventListener('submit', async function(e) {
+        document.getElementById('pdf-form').addEventListener('submit', function(e) {
             e.preventDefault();
-            
-            const formData = new FormData(this);
+            submitPDF(new FormData(this));
+        });
+        
+        async function submitPDF(formData) {
             const button = document.getElementById('pdf-btn');
             
             button.disabled = true;
@@ -553,11 +1072,19 @@ The system will extract all 'SKU: [value]' patterns from your text."
                 });
                 
                 const result = await response.json();
+                showDiagnostics(result.diagnostics);
+                if (result.success || result.preview) {
+                    showPreview(result.preview);
+                    showTeachPanel('pdf-teach', result.unmatched, 'pdf-mapping');
+                }
                 
                 if (result.success) {
-                    showStatus('pdf-status', 'success', result.message, result.output_url);
+                    showStatus('pdf-status', 'success', result.message, result.output_url, result.downloads);
+                    if (result.run_id) {
+                        document.getElementById('pdf-status').innerHTML += '<br><a href="/pack-station?run=' + result.run_id + '" class="download-link" target="_blank">🔍 Open pack station for this run</a>';
+                    }
                 } else {
-                    showStatus('pdf-status', 'error', '❌ Error: ' + result.message);
+                    showStatus('pdf-status', 'error', '❌ Error: ' + result.message, null, result.downloads);
                 }
             } catch (error) {
                 showStatus('pdf-status', 'error', '❌ Network error: ' + error.message);
@@ -565,8 +1092,47 @@ The system will extract all 'SKU: [value]' patterns from your text."
                 button.disabled = false;
                 button.textContent = '📄 Process PDF';
             }
+        }
+        
+        // Overlay style presets
+        async function loadOverlayPresets() {
+            const select = document.getElementById('overlay-preset');
+            const current = select.value;
+            try {
+                const response = await fetch('/overlay-presets');
+                const result = await response.json();
+                select.innerHTML = '<option value="">Default</option>';
+                (result.presets || []).forEach(function(preset) {
+                    const option = document.createElement('option');
+                    option.value = preset.name;
+                    option.textContent = preset.name;
+                    select.appendChild(option);
+                });
+                select.value = current;
+            } catch (error) {
+                console.error('Failed to load overlay presets', error);
+            }
+        }
+        
+        document.getElementById('save-overlay-preset').addEventListener('click', async function() {
+            const status = document.getElementById('overlay-preset-status');
+            const formData = new FormData();
+            formData.append('name', document.getElementById('overlay-preset-name').value);
+            ['overlayTemplate', 'overlayFont', 'overlayFontSize', 'overlayColor', 'overlayOpacity', 'overlayAnchor'].forEach(function(name) {
+                formData.append(name, document.querySelector('[name="' + name + '"]').value);
+            });
+            
+            const response = await fetch('/overlay-presets', {method: 'POST', body: formData});
+            const result = await response.json();
+            status.textContent = result.success ? '✅ ' + result.message : '❌ ' + result.message;
+            if (result.success) {
+                await loadOverlayPresets();
+                document.getElementById('overlay-preset').value = document.getElementById('overlay-preset-name').value.trim();
+            }
         });
         
+        loadOverlayPresets();
+        
         // SKU Form Handler
         document.getElementById('sku-form').addEventListener('submit', async function(e) {
             e.preventDefault();
@@ -588,6 +1154,7 @@ The system will extract all 'SKU: [value]' patterns from your text."
                 
                 if (result.success) {
                     showStatus('sku-status', 'success', result.message, result.output_url);
+                    showTeachPanel('sku-teach', result.unmatched, 'sku-mapping');
                 } else {
                     showStatus('sku-status', 'error', '❌ Error: ' + result.message);
                 }
@@ -636,8 +1203,8 @@ The system will extract all 'SKU: [value]' patterns from your text."
                 const radio = this.querySelector('input[type="radio"]');
                 radio.checked = true;
                 
-                // Update visual state
-                document.querySelectorAll('.radio-option').forEach(opt => {
+                // Update visual state within this group only
+                this.closest('.radio-group').querySelectorAll('.radio-option').forEach(opt => {
                     opt.style.borderColor = '#ddd';
                     opt.style.background = 'white';
                 });
@@ -662,3 +1229,329 @@ The system will extract all 'SKU: [value]' patterns from your text."
 		log.Printf("Failed to write template content: %v", err)
 	}
 }
+
+func createPackStationTemplate() {
+	templatePath := filepath.Join("templates", "pack_station.html")
+
+	// Check if template already exists
+	if _, err := os.Stat(templatePath); err == nil {
+		return // File already exists
+	}
+
+	templateContent := `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    <style>
+        * {
+            margin: 0;
+            padding: 0;
+            box-sizing: border-box;
+        }
+
+        body {
+            font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif;
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            min-height: 100vh;
+            padding: 20px;
+        }
+
+        .container {
+            max-width: 900px;
+            margin: 0 auto;
+            background: white;
+            border-radius: 15px;
+            box-shadow: 0 15px 35px rgba(0,0,0,0.1);
+            overflow: hidden;
+        }
+
+        .header {
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            color: white;
+            padding: 30px;
+            text-align: center;
+        }
+
+        .header h1 {
+            font-size: 2.2em;
+            margin-bottom: 10px;
+        }
+
+        .header a {
+            color: white;
+            opacity: 0.9;
+        }
+
+        .station {
+            padding: 30px 40px;
+        }
+
+        .scan-row {
+            display: grid;
+            grid-template-columns: 1fr 1fr 1fr;
+            gap: 15px;
+            margin-bottom: 20px;
+        }
+
+        .scan-row label {
+            display: block;
+            color: #333;
+            font-weight: bold;
+            margin-bottom: 8px;
+        }
+
+        .scan-input {
+            width: 100%;
+            padding: 14px 15px;
+            border: 2px solid #ddd;
+            border-radius: 8px;
+            font-size: 1.2em;
+            font-family: 'Courier New', monospace;
+        }
+
+        .scan-input:focus {
+            outline: none;
+            border-color: #667eea;
+            box-shadow: 0 0 0 3px rgba(102, 126, 234, 0.1);
+        }
+
+        .verdict {
+            padding: 25px;
+            border-radius: 10px;
+            font-size: 1.6em;
+            font-weight: bold;
+            text-align: center;
+            margin-bottom: 20px;
+            display: none;
+        }
+
+        .verdict-pass {
+            background: #d4edda;
+            color: #155724;
+            border: 2px solid #c3e6cb;
+        }
+
+        .verdict-fail {
+            background: #f8d7da;
+            color: #721c24;
+            border: 2px solid #f5c6cb;
+        }
+
+        .verdict-info {
+            background: #f8f9ff;
+            color: #333;
+            border: 2px solid #e8f0ff;
+        }
+
+        table {
+            width: 100%;
+            border-collapse: collapse;
+            margin-bottom: 20px;
+        }
+
+        th, td {
+            padding: 10px;
+            border-bottom: 1px solid #eee;
+            text-align: left;
+        }
+
+        th {
+            background: #f8f9ff;
+            color: #333;
+        }
+
+        .done td {
+            color: #155724;
+            background: #f3fbf5;
+        }
+
+        .summary {
+            color: #666;
+            margin-bottom: 15px;
+        }
+
+        .process-btn {
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            color: white;
+            border: none;
+            padding: 12px 25px;
+            border-radius: 8px;
+            font-size: 1em;
+            font-weight: bold;
+            cursor: pointer;
+        }
+
+        .download-link {
+            display: inline-block;
+            margin-left: 15px;
+            color: #667eea;
+            text-decoration: none;
+            font-weight: bold;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{.Title}}</h1>
+            <p>Scan the order barcode, then each product barcode · <a href="/">Back to processor</a></p>
+        </div>
+
+        <div class="station">
+            <div class="scan-row">
+                <div>
+                    <label for="run-id">Run</label>
+                    <input type="text" id="run-id" class="scan-input" placeholder="Run ID" autocomplete="off">
+                </div>
+                <div>
+                    <label for="order-number">Order Number</label>
+                    <input type="text" id="order-number" class="scan-input" placeholder="Scan or type order" autocomplete="off">
+                </div>
+                <div>
+                    <label for="product-scan">Product</label>
+                    <input type="text" id="product-scan" class="scan-input" placeholder="Scan product barcode" autocomplete="off">
+                </div>
+            </div>
+
+            <div id="verdict" class="verdict"></div>
+
+            <table id="order-lines" style="display: none;">
+                <thead>
+                    <tr><th>SKU</th><th>Thickness</th><th>Dimension</th><th>Packed</th></tr>
+                </thead>
+                <tbody></tbody>
+            </table>
+
+            <div id="run-summary" class="summary"></div>
+
+            <button type="button" class="process-btn" id="export-btn">📥 Export verification CSV</button>
+            <span id="export-link"></span>
+        </div>
+    </div>
+
+    <script>
+        const runInput = document.getElementById('run-id');
+        const orderInput = document.getElementById('order-number');
+        const scanInput = document.getElementById('product-scan');
+        const verdict = document.getElementById('verdict');
+
+        runInput.value = new URLSearchParams(window.location.search).get('run') || '';
+        (runInput.value ? orderInput : runInput).focus();
+
+        function showVerdict(type, message) {
+            verdict.className = 'verdict verdict-' + type;
+            verdict.textContent = message;
+            verdict.style.display = 'block';
+        }
+
+        function showLines(lines) {
+            const table = document.getElementById('order-lines');
+            const body = table.querySelector('tbody');
+            body.innerHTML = '';
+            (lines || []).forEach(function(line) {
+                const row = document.createElement('tr');
+                if (line.packed >= line.quantity) {
+                    row.className = 'done';
+                }
+                [line.sku, line.thickness, line.dimension, line.packed + ' / ' + line.quantity].forEach(function(text) {
+                    const cell = document.createElement('td');
+                    cell.textContent = text;
+                    row.appendChild(cell);
+                });
+                body.appendChild(row);
+            });
+            table.style.display = lines && lines.length ? 'table' : 'none';
+        }
+
+        async function refreshSummary() {
+            if (!runInput.value) {
+                return;
+            }
+            const response = await fetch('/verify?run=' + encodeURIComponent(runInput.value));
+            const result = await response.json();
+            const summary = document.getElementById('run-summary');
+            if (!result.success) {
+                summary.textContent = result.message;
+                return;
+            }
+            const counts = {};
+            Object.values(result.orders || {}).forEach(function(order) {
+                counts[order.status] = (counts[order.status] || 0) + 1;
+            });
+            summary.textContent = Object.keys(counts).sort().map(function(status) {
+                return status + ': ' + counts[status];
+            }).join(' · ');
+        }
+
+        orderInput.addEventListener('keydown', async function(e) {
+            if (e.key !== 'Enter' || !this.value.trim()) {
+                return;
+            }
+            const params = new URLSearchParams({run: runInput.value, order: this.value.trim()});
+            const response = await fetch('/verify?' + params);
+            const result = await response.json();
+            if (result.success) {
+                this.value = result.order;
+                showVerdict('info', 'Order ' + result.order + ' · ' + result.status);
+                showLines(result.lines);
+                scanInput.focus();
+            } else {
+                showVerdict('fail', '❌ ' + result.message);
+                showLines([]);
+                this.select();
+            }
+        });
+
+        scanInput.addEventListener('keydown', async function(e) {
+            if (e.key !== 'Enter' || !this.value.trim()) {
+                return;
+            }
+            const formData = new FormData();
+            formData.append('run', runInput.value);
+            formData.append('order', orderInput.value.trim());
+            formData.append('scan', this.value.trim());
+            this.value = '';
+
+            const response = await fetch('/verify', {method: 'POST', body: formData});
+            const result = await response.json();
+            if (!result.success) {
+                showVerdict('fail', '❌ ' + result.message);
+                return;
+            }
+
+            showLines(result.lines);
+            if (!result.pass) {
+                showVerdict('fail', '❌ FAIL · ' + result.message);
+            } else if (result.status === 'Verified') {
+                showVerdict('pass', '✅ ORDER VERIFIED · ' + result.message);
+                orderInput.value = '';
+                orderInput.focus();
+            } else {
+                showVerdict('pass', '✅ PASS · ' + result.message);
+            }
+            refreshSummary();
+        });
+
+        document.getElementById('export-btn').addEventListener('click', async function() {
+            const response = await fetch('/verify/export?run=' + encodeURIComponent(runInput.value));
+            const result = await response.json();
+            const link = document.getElementById('export-link');
+            if (result.success) {
+                link.innerHTML = '<a href="' + result.output_url + '" class="download-link" download>📥 ' + result.file_name + '</a>';
+            } else {
+                link.textContent = '❌ ' + result.message;
+            }
+        });
+
+        runInput.addEventListener('change', refreshSummary);
+        refreshSummary();
+    </script>
+</body>
+</html>`
+
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		log.Printf("Failed to create pack station template: %v", err)
+	}
+}
@@ -36,5 +36,32 @@ else
     echo "✅ pdftk found"
 fi
 
+# Check for tesseract (needed for OCR of scanned pages, optional)
+if ! command -v tesseract &> /dev/null; then
+    echo "⚠️  Warning: tesseract not found. Scanned invoice pages will not be read."
+    echo "📥 Install tesseract (optional):"
+    echo "   macOS: brew install tesseract"
+    echo "   Ubuntu: sudo apt-get install tesseract-ocr"
+    echo ""
+else
+    echo "✅ tesseract found"
+fi
+
+# Fetch Noto Sans Devanagari (used for Hindi text in generated PDFs)
+NOTO_URL="https://github.com/notofonts/notofonts.github.io/raw/main/fonts/NotoSansDevanagari/hinted/ttf"
+for font in NotoSansDevanagari-Regular.ttf NotoSansDevanagari-Bold.ttf; do
+    if [ ! -f "fonts/$font" ]; then
+        echo "📥 Downloading $font..."
+        if ! curl -fsSL -o "fonts/$font" "$NOTO_URL/$font"; then
+            rm -f "fonts/$font"
+            echo "⚠️  Warning: could not download $font. Hindi text in PDFs will print as blanks."
+            echo ""
+        fi
+    fi
+done
+if [ -f fonts/NotoSansDevanagari-Regular.ttf ]; then
+    echo "✅ Devanagari PDF font found"
+fi
+
 echo "🚀 Starting web server..."
-go run main.go
+go run main.go "$@"
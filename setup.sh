@@ -53,7 +53,7 @@ else
 fi
 
 echo "🚀 Starting web server..."
-go run main.go
+go run main.go "$@"
 EOF
 
 chmod +x run.sh